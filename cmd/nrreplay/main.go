@@ -0,0 +1,70 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Command nrreplay resends payloads captured by the debug sink of the New
+// Relic exporter, e.g. data lost to an ingest outage or a bad API key.
+//
+// Usage:
+//
+//	nrreplay [flags] path...
+//
+// Each path is a file written by the debug sink, or a directory of payload
+// files. The payloads are validated before any is sent. The API key is read
+// from the NEW_RELIC_API_KEY environment variable unless set with -api-key.
+//
+// Flags:
+//
+//	-api-key string   API key to send the payloads with.
+//	-region string    Send to the endpoints of the region, "us" or "eu",
+//	                  instead of the endpoints the payloads were captured for.
+//	-service string   Rewrite the service name of the payloads.
+//	-rate float       Maximum number of requests per second, unlimited if zero.
+//	-dry-run          Validate the payloads and print where they would be sent.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	var r replayer
+	flag.StringVar(&r.apiKey, "api-key", os.Getenv("NEW_RELIC_API_KEY"), "API key to send the payloads with")
+	flag.StringVar(&r.region, "region", "", `send to the endpoints of the region, "us" or "eu"`)
+	flag.StringVar(&r.service, "service", "", "rewrite the service name of the payloads")
+	flag.Float64Var(&r.rate, "rate", 0, "maximum number of requests per second, unlimited if zero")
+	flag.BoolVar(&r.dryRun, "dry-run", false, "validate the payloads and print where they would be sent")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] path...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if r.apiKey == "" && !r.dryRun {
+		fmt.Fprintln(os.Stderr, "missing API key: set -api-key or NEW_RELIC_API_KEY")
+		os.Exit(2)
+	}
+	r.client = &http.Client{Timeout: 30 * time.Second}
+	r.out = os.Stdout
+
+	var payloads []payload
+	for _, path := range flag.Args() {
+		ps, err := readPayloads(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		payloads = append(payloads, ps...)
+	}
+	if failed := r.replay(context.Background(), payloads); failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d payloads failed\n", failed, len(payloads))
+		os.Exit(1)
+	}
+}
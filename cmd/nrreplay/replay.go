@@ -0,0 +1,299 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic"
+)
+
+const userAgent = "NewRelic-Go-OpenTelemetry-Replay"
+
+// regionEndpoints are the endpoints of each signal in each New Relic region.
+var regionEndpoints = map[string]map[string]string{
+	"us": {
+		newrelic.SignalSpans:   "https://trace-api.newrelic.com/trace/v1",
+		newrelic.SignalMetrics: "https://metric-api.newrelic.com/metric/v1",
+		newrelic.SignalEvents:  "https://insights-collector.newrelic.com/v1/accounts/events",
+		newrelic.SignalLogs:    "https://log-api.newrelic.com/log/v1",
+	},
+	"eu": {
+		newrelic.SignalSpans:   "https://trace-api.eu.newrelic.com/trace/v1",
+		newrelic.SignalMetrics: "https://metric-api.eu.newrelic.com/metric/v1",
+		newrelic.SignalEvents:  "https://insights-collector.eu01.nr-data.net/v1/accounts/events",
+		newrelic.SignalLogs:    "https://log-api.eu.newrelic.com/log/v1",
+	},
+}
+
+// serviceAttributes are the attributes holding the service name.
+var serviceAttributes = []string{"service.name", "entity.name", "appName"}
+
+// payload is a captured payload and where it was read from.
+type payload struct {
+	newrelic.DebugPayload
+	source string
+}
+
+// readPayloads reads the payloads of the debug sink file at path, or of the
+// payload files of the directory at path in name order.
+func readPayloads(path string) ([]payload, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return readPayloadFile(path)
+	}
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var payloads []payload
+	for _, f := range files {
+		ps, err := readPayloadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, ps...)
+	}
+	return payloads, nil
+}
+
+// readPayloadFile reads the payloads written to the file at path.
+func readPayloadFile(path string) ([]payload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var payloads []payload
+	for dec := json.NewDecoder(f); ; {
+		var p newrelic.DebugPayload
+		if err := dec.Decode(&p); err == io.EOF {
+			return payloads, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: payload %d: %w", path, len(payloads)+1, err)
+		}
+		payloads = append(payloads, payload{
+			DebugPayload: p,
+			source:       fmt.Sprintf("%s#%d", path, len(payloads)+1),
+		})
+	}
+}
+
+// validate returns an error if p is not a valid payload of its signal.
+func validate(p newrelic.DebugPayload) error {
+	switch p.Signal {
+	case newrelic.SignalEvents:
+		var events []map[string]json.RawMessage
+		if err := json.Unmarshal(p.Payload, &events); err != nil {
+			return fmt.Errorf("invalid events payload: %w", err)
+		}
+		for _, e := range events {
+			if _, ok := e["eventType"]; !ok {
+				return errors.New("event without eventType")
+			}
+		}
+		return nil
+	case newrelic.SignalSpans, newrelic.SignalMetrics, newrelic.SignalLogs:
+		var batches []map[string]json.RawMessage
+		if err := json.Unmarshal(p.Payload, &batches); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", p.Signal, err)
+		}
+		if len(batches) == 0 {
+			return fmt.Errorf("empty %s payload", p.Signal)
+		}
+		for _, b := range batches {
+			var items []map[string]interface{}
+			if err := json.Unmarshal(b[p.Signal], &items); err != nil {
+				return fmt.Errorf("invalid %s: %w", p.Signal, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown signal: %q", p.Signal)
+}
+
+// rewriteService returns the payload with the service name attributes of the
+// common blocks, items, and events set to service.
+func rewriteService(p json.RawMessage, service string) (json.RawMessage, error) {
+	var v interface{}
+	if err := json.Unmarshal(p, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(rewriteValue(v, service))
+}
+
+func rewriteValue(v interface{}, service string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range val {
+			if _, ok := elem.(string); ok && isServiceAttribute(k) {
+				val[k] = service
+			} else {
+				val[k] = rewriteValue(elem, service)
+			}
+		}
+	case []interface{}:
+		for i, elem := range val {
+			val[i] = rewriteValue(elem, service)
+		}
+	}
+	return v
+}
+
+func isServiceAttribute(k string) bool {
+	for _, a := range serviceAttributes {
+		if k == a {
+			return true
+		}
+	}
+	return false
+}
+
+// replayer sends captured payloads to New Relic.
+type replayer struct {
+	apiKey  string
+	region  string
+	service string
+	// rate is the maximum number of requests per second, unlimited if not
+	// positive.
+	rate   float64
+	dryRun bool
+
+	client *http.Client
+	out    io.Writer
+}
+
+// endpoint returns the URL p is sent to: the endpoint of its signal in the
+// region of r, or else the URL it was captured for.
+func (r *replayer) endpoint(p newrelic.DebugPayload) (string, error) {
+	if r.region != "" {
+		endpoints, ok := regionEndpoints[r.region]
+		if !ok {
+			return "", fmt.Errorf("unknown region: %q", r.region)
+		}
+		return endpoints[p.Signal], nil
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil || !u.IsAbs() {
+		return "", fmt.Errorf("payload URL %q is not absolute, set a region", p.URL)
+	}
+	return p.URL, nil
+}
+
+// prepare validates p and returns its endpoint and body.
+func (r *replayer) prepare(p newrelic.DebugPayload) (string, []byte, error) {
+	if err := validate(p); err != nil {
+		return "", nil, err
+	}
+	endpoint, err := r.endpoint(p)
+	if err != nil {
+		return "", nil, err
+	}
+	body := []byte(p.Payload)
+	if r.service != "" {
+		if body, err = rewriteService(p.Payload, r.service); err != nil {
+			return "", nil, err
+		}
+	}
+	return endpoint, body, nil
+}
+
+// send sends body gzip compressed to endpoint.
+func (r *replayer) send(ctx context.Context, endpoint string, body []byte) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, &buf)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Api-Key", r.apiKey)
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// replay validates all payloads, then sends them unless r is a dry run. It
+// returns the number of payloads that failed. Nothing is sent if any payload
+// is invalid.
+func (r *replayer) replay(ctx context.Context, payloads []payload) int {
+	type request struct {
+		source   string
+		endpoint string
+		body     []byte
+	}
+	var requests []request
+	var invalid int
+	for _, p := range payloads {
+		endpoint, body, err := r.prepare(p.DebugPayload)
+		if err != nil {
+			fmt.Fprintf(r.out, "%s: invalid: %v\n", p.source, err)
+			invalid++
+			continue
+		}
+		requests = append(requests, request{source: p.source, endpoint: endpoint, body: body})
+	}
+	if invalid > 0 {
+		return invalid
+	}
+
+	var interval time.Duration
+	if r.rate > 0 {
+		interval = time.Duration(float64(time.Second) / r.rate)
+	}
+	var failed int
+	var last time.Time
+	for _, req := range requests {
+		if r.dryRun {
+			fmt.Fprintf(r.out, "%s: would send %d bytes to %s\n", req.source, len(req.body), req.endpoint)
+			continue
+		}
+		if wait := interval - time.Since(last); !last.IsZero() && wait > 0 {
+			time.Sleep(wait)
+		}
+		last = time.Now()
+		if err := r.send(ctx, req.endpoint, req.body); err != nil {
+			fmt.Fprintf(r.out, "%s: failed: %v\n", req.source, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(r.out, "%s: sent %d bytes to %s\n", req.source, len(req.body), req.endpoint)
+	}
+	return failed
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic"
+)
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		signal  string
+		payload string
+		valid   bool
+	}{
+		{signal: newrelic.SignalSpans, payload: `[{"common":{},"spans":[{"id":"1"}]}]`, valid: true},
+		{signal: newrelic.SignalMetrics, payload: `[{"metrics":[]}]`, valid: true},
+		{signal: newrelic.SignalLogs, payload: `[{"logs":[{"message":"m"}]}]`, valid: true},
+		{signal: newrelic.SignalEvents, payload: `[{"eventType":"A"}]`, valid: true},
+		{signal: newrelic.SignalEvents, payload: `[{"name":"A"}]`},
+		{signal: newrelic.SignalSpans, payload: `[{"metrics":[]}]`},
+		{signal: newrelic.SignalSpans, payload: `[]`},
+		{signal: newrelic.SignalSpans, payload: `{}`},
+		{signal: "traces", payload: `[{"traces":[]}]`},
+	} {
+		err := validate(newrelic.DebugPayload{Signal: test.signal, Payload: json.RawMessage(test.payload)})
+		if test.valid && err != nil {
+			t.Errorf("%s %s: unexpected error: %v", test.signal, test.payload, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s %s: expected an error", test.signal, test.payload)
+		}
+	}
+}
+
+func TestRewriteService(t *testing.T) {
+	got, err := rewriteService(json.RawMessage(`[{"common":{"attributes":{"service.name":"a","host":"h"}},"spans":[{"attributes":{"entity.name":"a","n":1}}]}]`), "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"common":{"attributes":{"host":"h","service.name":"b"}},"spans":[{"attributes":{"entity.name":"b","n":1}}]}]`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func writePayloads(t *testing.T, path string, payloads ...newrelic.DebugPayload) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, p := range payloads {
+		if err := enc.Encode(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadPayloads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nrreplay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	spans := newrelic.DebugPayload{Signal: newrelic.SignalSpans, Payload: json.RawMessage(`[{"spans":[]}]`)}
+	metrics := newrelic.DebugPayload{Signal: newrelic.SignalMetrics, Payload: json.RawMessage(`[{"metrics":[]}]`)}
+	writePayloads(t, filepath.Join(dir, "b.json"), metrics)
+	writePayloads(t, filepath.Join(dir, "a.json"), spans, spans)
+	writePayloads(t, filepath.Join(dir, "c.txt"), metrics)
+
+	payloads, err := readPayloads(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signals []string
+	for _, p := range payloads {
+		signals = append(signals, p.Signal)
+	}
+	if got, want := strings.Join(signals, ","), "spans,spans,metrics"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := ioutil.WriteFile(bad, []byte(`{"signal":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPayloads(bad); err == nil {
+		t.Error("expected an error reading a truncated file")
+	}
+}
+
+// ingest is a fake New Relic ingest endpoint.
+type ingest struct {
+	lock     sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (i *ingest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gz, err := gzip.NewReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body, _ := ioutil.ReadAll(gz)
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.requests = append(i.requests, r)
+	i.bodies = append(i.bodies, string(body))
+	if i.status != 0 {
+		w.WriteHeader(i.status)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestReplay(t *testing.T) {
+	srv := &ingest{}
+	s := httptest.NewServer(srv)
+	defer s.Close()
+
+	payloads := []payload{
+		{DebugPayload: newrelic.DebugPayload{URL: s.URL + "/trace/v1", Signal: newrelic.SignalSpans, Payload: json.RawMessage(`[{"common":{"attributes":{"service.name":"a"}},"spans":[]}]`)}},
+		{DebugPayload: newrelic.DebugPayload{URL: s.URL + "/metric/v1", Signal: newrelic.SignalMetrics, Payload: json.RawMessage(`[{"metrics":[]}]`)}},
+	}
+
+	var out bytes.Buffer
+	r := &replayer{apiKey: "key", service: "b", dryRun: true, client: s.Client(), out: &out}
+	if failed := r.replay(context.Background(), payloads); failed != 0 {
+		t.Errorf("dry run: %d payloads failed: %s", failed, out.String())
+	}
+	if len(srv.requests) != 0 {
+		t.Errorf("dry run sent %d requests", len(srv.requests))
+	}
+
+	r.dryRun = false
+	r.rate = 20
+	start := time.Now()
+	if failed := r.replay(context.Background(), payloads); failed != 0 {
+		t.Errorf("%d payloads failed: %s", failed, out.String())
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("rate limit not applied: sent in %v", elapsed)
+	}
+	if len(srv.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(srv.requests))
+	}
+	if got := srv.requests[0].URL.Path; got != "/trace/v1" {
+		t.Errorf("path: got %s, want /trace/v1", got)
+	}
+	if got := srv.requests[0].Header.Get("Api-Key"); got != "key" {
+		t.Errorf("Api-Key: got %q, want key", got)
+	}
+	if !strings.Contains(srv.bodies[0], `"service.name":"b"`) {
+		t.Errorf("service name not rewritten: %s", srv.bodies[0])
+	}
+
+	srv.status = http.StatusForbidden
+	if failed := r.replay(context.Background(), payloads); failed != 2 {
+		t.Errorf("expected 2 failed payloads, got %d", failed)
+	}
+
+	// Nothing is sent if any payload is invalid.
+	srv.requests = nil
+	invalid := append(payloads, payload{DebugPayload: newrelic.DebugPayload{URL: "/log/v1", Signal: newrelic.SignalLogs, Payload: json.RawMessage(`[{"logs":[]}]`)}})
+	if failed := r.replay(context.Background(), invalid); failed != 1 {
+		t.Errorf("expected 1 invalid payload, got %d", failed)
+	}
+	if len(srv.requests) != 0 {
+		t.Errorf("sent %d requests with an invalid payload", len(srv.requests))
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	p := newrelic.DebugPayload{URL: "https://trace-api.newrelic.com/trace/v1", Signal: newrelic.SignalSpans}
+	r := &replayer{region: "eu"}
+	if got, err := r.endpoint(p); err != nil || got != "https://trace-api.eu.newrelic.com/trace/v1" {
+		t.Errorf("eu endpoint: got %q, %v", got, err)
+	}
+	r.region = "ap"
+	if _, err := r.endpoint(p); err == nil {
+		t.Error("expected an error for an unknown region")
+	}
+	r.region = ""
+	if got, err := r.endpoint(p); err != nil || got != p.URL {
+		t.Errorf("payload endpoint: got %q, %v", got, err)
+	}
+}
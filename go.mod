@@ -3,11 +3,15 @@ module github.com/newrelic/opentelemetry-exporter-go
 go 1.14
 
 require (
+	github.com/golang/protobuf v1.5.0
 	github.com/newrelic/newrelic-telemetry-sdk-go v0.7.1
 	go.opentelemetry.io/otel v0.20.0
+	go.opentelemetry.io/otel/exporters/otlp v0.20.0
 	go.opentelemetry.io/otel/metric v0.20.0
 	go.opentelemetry.io/otel/sdk v0.20.0
 	go.opentelemetry.io/otel/sdk/export/metric v0.20.0
 	go.opentelemetry.io/otel/sdk/metric v0.20.0
 	go.opentelemetry.io/otel/trace v0.20.0
+	go.opentelemetry.io/proto/otlp v0.7.0
+	google.golang.org/grpc v1.43.0
 )
@@ -0,0 +1,186 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// redacted replaces redacted header and attribute values.
+const redacted = "REDACTED"
+
+// redactedHeaders are the headers New Relic API keys are sent with.
+var redactedHeaders = []string{"Api-Key", "X-Insert-Key", "X-License-Key"}
+
+// AuditRecord describes a request sent to New Relic.
+type AuditRecord struct {
+	// Time is when the request was sent.
+	Time time.Time `json:"time"`
+	// Destination is the name of the destination of the request.
+	Destination string `json:"destination"`
+	// URL is the New Relic endpoint the request is sent to.
+	URL string `json:"url"`
+	// Header is the request header with the API key redacted.
+	Header http.Header `json:"header"`
+	// Size is the size of the compressed request body in bytes.
+	Size int `json:"size"`
+	// Signal is the kind of data sent and Items the number of spans,
+	// metrics, events, or logs sent.
+	Signal string `json:"signal"`
+	Items  int    `json:"items"`
+	// StatusCode is the status code of the response, or zero if the request
+	// failed without a response.
+	StatusCode int `json:"status_code,omitempty"`
+	// Error is the error the request failed with, if any.
+	Error string `json:"error,omitempty"`
+	// Latency is the time until the response was received.
+	Latency time.Duration `json:"latency"`
+	// Body is the uncompressed JSON request body with the sensitive
+	// attributes redacted.
+	Body json.RawMessage `json:"body"`
+}
+
+// AuditLogger records the requests sent to New Relic. It is called
+// concurrently.
+type AuditLogger func(AuditRecord)
+
+// NewAuditLogWriter returns an AuditLogger writing each record to w as a line
+// of JSON. Write errors are sent to the OpenTelemetry error handler.
+func NewAuditLogWriter(w io.Writer) AuditLogger {
+	var lock sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(r AuditRecord) {
+		lock.Lock()
+		defer lock.Unlock()
+		if err := enc.Encode(r); err != nil {
+			otel.Handle(fmt.Errorf("audit log: %w", err))
+		}
+	}
+}
+
+// audit is the audit log configuration of an Exporter.
+type audit struct {
+	logger AuditLogger
+	// sensitive are the attributes redacted from request bodies.
+	sensitive map[string]bool
+}
+
+// auditTransport records the requests sent with base to an AuditLogger.
+type auditTransport struct {
+	base        http.RoundTripper
+	destination string
+	logger      AuditLogger
+	// sensitive are the attributes redacted from request bodies.
+	sensitive map[string]bool
+}
+
+func (t auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	r := AuditRecord{
+		Time:        time.Now(),
+		Destination: t.destination,
+		URL:         req.URL.String(),
+		Header:      redactHeader(req.Header),
+		Signal:      payloadSignal(body),
+		Body:        t.redactBody(body),
+	}
+	r.Items = payloadItems(body, r.Signal)
+	if req.ContentLength > 0 {
+		r.Size = int(req.ContentLength)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	r.Latency = time.Since(r.Time)
+	if err != nil {
+		r.Error = err.Error()
+	} else {
+		r.StatusCode = resp.StatusCode
+	}
+	t.logger(r)
+	return resp, err
+}
+
+// redactHeader returns a copy of h with the API key headers redacted.
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range redactedHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(k)]; ok {
+			h.Set(k, redacted)
+		}
+	}
+	return h
+}
+
+// redactBody returns the JSON body with the values of the sensitive
+// attributes redacted at any depth. Bodies that are not JSON are recorded
+// as a JSON string.
+func (t auditTransport) redactBody(body []byte) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		b, _ := json.Marshal(string(body))
+		return b
+	}
+	if len(t.sensitive) == 0 {
+		return body
+	}
+	b, err := json.Marshal(redactValue(v, t.sensitive))
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// redactValue redacts the values of the sensitive keys of the objects in v.
+func redactValue(v interface{}, sensitive map[string]bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range val {
+			if sensitive[k] {
+				val[k] = redacted
+			} else {
+				val[k] = redactValue(elem, sensitive)
+			}
+		}
+	case []interface{}:
+		for i, elem := range val {
+			val[i] = redactValue(elem, sensitive)
+		}
+	}
+	return v
+}
+
+// payloadItems returns the number of items of the signal in the JSON
+// payload of a New Relic API.
+func payloadItems(payload []byte, signal string) int {
+	var items int
+	switch signal {
+	case SignalEvents:
+		var events []json.RawMessage
+		if err := json.Unmarshal(payload, &events); err == nil {
+			items = len(events)
+		}
+	case SignalSpans, SignalMetrics, SignalLogs:
+		var batches []map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &batches); err != nil {
+			return 0
+		}
+		for _, b := range batches {
+			var list []json.RawMessage
+			if err := json.Unmarshal(b[signal], &list); err == nil {
+				items += len(list)
+			}
+		}
+	}
+	return items
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithAuditLog(NewAuditLogWriter(&buf), "user.email"))
+
+	spans := newTestSpans("a", "b")
+	spans[0].Attributes = []attribute.KeyValue{
+		attribute.String("user.email", "user@example.com"),
+		attribute.String("user.id", "1"),
+	}
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	if got := len(mockt.Spans()); got != 2 {
+		t.Errorf("expected 2 spans sent, got %d", got)
+	}
+	out := buf.String()
+	if strings.Contains(out, testAPIKey) {
+		t.Errorf("API key not redacted: %s", out)
+	}
+	if strings.Contains(out, "user@example.com") {
+		t.Errorf("sensitive attribute not redacted: %s", out)
+	}
+
+	var r AuditRecord
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&r); err != nil {
+		t.Fatalf("decoding audit record: %v", err)
+	}
+	if r.Destination != DefaultDestination {
+		t.Errorf("destination: got %q, want %q", r.Destination, DefaultDestination)
+	}
+	if r.Signal != SignalSpans || r.Items != 2 {
+		t.Errorf("got %d %s, want 2 spans", r.Items, r.Signal)
+	}
+	if r.StatusCode != 200 {
+		t.Errorf("status code: got %d, want 200", r.StatusCode)
+	}
+	if r.Size <= 0 {
+		t.Errorf("size: got %d", r.Size)
+	}
+	if got := r.Header.Get("Api-Key"); got != redacted {
+		t.Errorf("Api-Key header: got %q, want %q", got, redacted)
+	}
+	var batches []Data
+	if err := json.Unmarshal(r.Body, &batches); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	for _, s := range batches[0].Spans {
+		if email, ok := s.Attributes["user.email"]; ok && email != redacted {
+			t.Errorf("user.email: got %v, want %s", email, redacted)
+		}
+		if id, ok := s.Attributes["user.id"]; ok && id != "1" {
+			t.Errorf("user.id: got %v, want 1", id)
+		}
+	}
+}
+
+func TestAuditLogDebugOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	e := newTestExporter(t, "service", &MockTransport{},
+		WithAuditLog(NewAuditLogWriter(&buf)),
+		WithDebugSink(dir, DebugOnly()),
+	)
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, newTestSpans("a")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	// The payload is written to the debug sink but not sent.
+	if buf.Len() != 0 {
+		t.Errorf("unsent request recorded: %s", buf.String())
+	}
+	if h := e.Health()[0]; h.Requests != 0 {
+		t.Errorf("unsent request counted in health: %+v", h)
+	}
+}
+
+func TestPayloadItems(t *testing.T) {
+	for _, test := range []struct {
+		payload string
+		want    int
+	}{
+		{payload: `[{"common":{},"spans":[{},{}]},{"spans":[{}]}]`, want: 3},
+		{payload: `[{"common":{},"metrics":[{}]}]`, want: 1},
+		{payload: `[{"logs":[]}]`, want: 0},
+		{payload: `[{"eventType":"A"},{"eventType":"B"}]`, want: 2},
+		{payload: `not json`, want: 0},
+	} {
+		p := []byte(test.payload)
+		if got := payloadItems(p, payloadSignal(p)); got != test.want {
+			t.Errorf("payloadItems(%s): got %d, want %d", test.payload, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel"
+)
+
+// DebugPayload is a payload written by a debug sink. Each is a JSON object
+// holding the uncompressed JSON payload exactly as it is sent to New Relic.
+type DebugPayload struct {
+	// Time is when the payload was sent.
+	Time time.Time `json:"time"`
+	// URL is the New Relic endpoint the payload is sent to.
+	URL string `json:"url"`
+	// Signal is the kind of data of the payload: "spans", "metrics",
+	// "events", or "logs".
+	Signal string `json:"signal"`
+	// Payload is the payload.
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Signals of debug payloads.
+const (
+	SignalSpans   = "spans"
+	SignalMetrics = "metrics"
+	SignalEvents  = "events"
+	SignalLogs    = "logs"
+)
+
+// payloadSignal returns the signal of the JSON payload of a New Relic API.
+// The Event API payload is a list of events, the payloads of the other APIs
+// are lists of batches with a common block.
+func payloadSignal(payload []byte) string {
+	var batches []map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &batches); err != nil {
+		return ""
+	}
+	for _, b := range batches {
+		for _, signal := range []string{SignalSpans, SignalMetrics, SignalLogs} {
+			if _, ok := b[signal]; ok {
+				return signal
+			}
+		}
+		if _, ok := b["eventType"]; ok {
+			return SignalEvents
+		}
+	}
+	return ""
+}
+
+// DebugSinkOption configures a debug sink.
+type DebugSinkOption func(*debugSink)
+
+// DebugPretty indents the written payloads.
+func DebugPretty() DebugSinkOption {
+	return func(s *debugSink) {
+		s.pretty = true
+	}
+}
+
+// DebugOnly writes the payloads instead of sending them to New Relic.
+func DebugOnly() DebugSinkOption {
+	return func(s *debugSink) {
+		s.only = true
+	}
+}
+
+// DebugRotate rotates the file payloads are written to once it reaches
+// maxBytes, keeping at most backups rotated files named after the file with a
+// ".1", ".2", ... suffix, the most recent first. It has no effect when
+// writing to stdout or a directory.
+func DebugRotate(maxBytes int64, backups int) DebugSinkOption {
+	return func(s *debugSink) {
+		s.maxBytes = maxBytes
+		s.backups = backups
+	}
+}
+
+// debugSink writes payloads to stdout, a file, or a directory with a file per
+// payload.
+type debugSink struct {
+	pretty   bool
+	only     bool
+	maxBytes int64
+	backups  int
+
+	lock sync.Mutex
+	// dir is the directory payloads are written to, if any.
+	dir string
+	seq int
+	// path, file, and size are the file payloads are written to, if any.
+	path string
+	file *os.File
+	size int64
+	// w is the writer payloads are written to if not a directory.
+	w io.Writer
+}
+
+// newDebugSink returns a debug sink writing to path: stdout if path is "-",
+// a new file for each payload if path is a directory, or else the file at
+// path, which payloads are appended to.
+func newDebugSink(path string, options ...DebugSinkOption) (*debugSink, error) {
+	s := &debugSink{}
+	for _, o := range options {
+		o(s)
+	}
+	if path == "-" {
+		s.w = os.Stdout
+		return s, nil
+	}
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		s.dir = path
+		return s, nil
+	}
+	s.path = path
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the file of the sink for appending.
+func (s *debugSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("debug sink: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("debug sink: %w", err)
+	}
+	s.file, s.w, s.size = f, f, fi.Size()
+	return nil
+}
+
+// rotate shifts the rotated files of the sink, moves its file to the first
+// one, and opens a new file.
+func (s *debugSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	if s.backups <= 0 {
+		if err := os.Remove(s.path); err != nil {
+			return err
+		}
+		return s.open()
+	}
+	for i := s.backups - 1; i > 0; i-- {
+		from := fmt.Sprintf("%s.%d", s.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+// write writes p.
+func (s *debugSink) write(p DebugPayload) error {
+	var b []byte
+	var err error
+	if s.pretty {
+		b, err = json.MarshalIndent(p, "", "  ")
+	} else {
+		b, err = json.Marshal(p)
+	}
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.dir != "" {
+		s.seq++
+		name := fmt.Sprintf("%s-%06d-%s.json", p.Time.UTC().Format("20060102T150405.000000000Z"), s.seq, p.Signal)
+		return ioutil.WriteFile(filepath.Join(s.dir, name), b, 0600)
+	}
+	if s.file != nil && s.maxBytes > 0 && s.size > 0 && s.size+int64(len(b)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("debug sink: rotate: %w", err)
+		}
+	}
+	n, err := s.w.Write(b)
+	s.size += int64(n)
+	return err
+}
+
+// close closes the file of the sink, if any.
+func (s *debugSink) close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// debugTransport writes the uncompressed body of each request to a debug sink
+// before sending it with base, unless the sink only writes payloads. Requests
+// the sink fails to write are still sent, and the error sent to the
+// OpenTelemetry error handler.
+type debugTransport struct {
+	base http.RoundTripper
+	sink *debugSink
+}
+
+func (t debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.write(req); err != nil {
+		if t.sink.only {
+			return nil, err
+		}
+		otel.Handle(err)
+	}
+
+	if t.sink.only {
+		return &http.Response{
+			Status:     "202 Accepted",
+			StatusCode: http.StatusAccepted,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(strings.NewReader("{}")),
+			Request:    req,
+		}, nil
+	}
+	return t.base.RoundTrip(req)
+}
+
+// write writes the body of req to the sink.
+func (t debugTransport) write(req *http.Request) error {
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+	payload := DebugPayload{
+		Time:    time.Now(),
+		URL:     req.URL.String(),
+		Signal:  payloadSignal(body),
+		Payload: json.RawMessage(body),
+	}
+	if !json.Valid(body) {
+		// Write invalid payloads as a JSON string so the sink output
+		// stays valid JSON.
+		payload.Payload, _ = json.Marshal(string(body))
+	}
+	return t.sink.write(payload)
+}
+
+// requestBody returns the uncompressed body of req and leaves the body of req
+// unread.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	compressed, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(compressed))
+	if req.Header.Get("Content-Encoding") != "gzip" {
+		return compressed, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
+
+// wrapTransport returns a telemetry option that wraps the transport of the
+// client of the harvester with wrap. The client is copied since it may be
+// shared with other harvesters.
+func wrapTransport(wrap func(http.RoundTripper) http.RoundTripper) func(*telemetry.Config) {
+	return func(cfg *telemetry.Config) {
+		client := &http.Client{}
+		if cfg.Client != nil {
+			*client = *cfg.Client
+		}
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		client.Transport = wrap(base)
+		cfg.Client = client
+	}
+}
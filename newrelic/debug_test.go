@@ -0,0 +1,225 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPayloadSignal(t *testing.T) {
+	for _, test := range []struct {
+		payload string
+		want    string
+	}{
+		{payload: `[{"common":{},"spans":[]}]`, want: SignalSpans},
+		{payload: `[{"common":{},"metrics":[]}]`, want: SignalMetrics},
+		{payload: `[{"common":{},"logs":[]}]`, want: SignalLogs},
+		{payload: `[{"eventType":"Transaction"}]`, want: SignalEvents},
+		{payload: `{}`, want: ""},
+		{payload: `not json`, want: ""},
+	} {
+		if got := payloadSignal([]byte(test.payload)); got != test.want {
+			t.Errorf("payloadSignal(%s): got %q, want %q", test.payload, got, test.want)
+		}
+	}
+}
+
+// readDebugPayloads decodes the debug payloads written to path.
+func readDebugPayloads(t *testing.T, path string) []DebugPayload {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var payloads []DebugPayload
+	for dec := json.NewDecoder(f); ; {
+		var p DebugPayload
+		if err := dec.Decode(&p); err == io.EOF {
+			return payloads
+		} else if err != nil {
+			t.Fatalf("decoding debug payload: %v", err)
+		}
+		payloads = append(payloads, p)
+	}
+}
+
+func TestDebugSinkFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "payloads.json")
+
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithDebugSink(path, DebugOnly()))
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, newTestSpans("a", "b")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	if got := len(mockt.Spans()); got != 0 {
+		t.Errorf("expected no spans sent, got %d", got)
+	}
+	payloads := readDebugPayloads(t, path)
+	if len(payloads) != 1 {
+		t.Fatalf("expected 1 payload, got %d", len(payloads))
+	}
+	p := payloads[0]
+	if p.Signal != SignalSpans {
+		t.Errorf("payload signal: got %q, want spans", p.Signal)
+	}
+	if !strings.HasSuffix(p.URL, "/trace/v1") {
+		t.Errorf("payload URL: got %q", p.URL)
+	}
+	var batches []Data
+	if err := json.Unmarshal(p.Payload, &batches); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	if len(batches) != 1 || len(batches[0].Spans) != 2 {
+		t.Errorf("expected 1 batch of 2 spans, got %s", p.Payload)
+	}
+}
+
+func TestDebugSinkClosedOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "payloads.json")
+
+	_, err = NewExporterWithOptions("service", "apiKey",
+		WithDebugSink(path),
+		WithDestinations(Destination{Name: "", APIKey: "key"}),
+	)
+	if err == nil {
+		t.Fatal("expected an error for the unnamed destination")
+	}
+
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("listing open files: %v", err)
+	}
+	for _, fd := range fds {
+		if target, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); target == path {
+			t.Errorf("debug sink file %s is still open", path)
+		}
+	}
+}
+
+func TestDebugSinkDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithDebugSink(dir, DebugPretty()))
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, newTestSpans("a")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	// The payload is written and sent.
+	if got := len(mockt.Spans()); got != 1 {
+		t.Errorf("expected 1 span sent, got %d", got)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*-spans.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 payload file, got %v", files)
+	}
+	b, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "\n  \"signal\": \"spans\"") {
+		t.Errorf("payload not indented: %s", b)
+	}
+}
+
+func TestDebugSinkWriteError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithDebugSink(dir))
+	// Payloads can no longer be written to the directory.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, newTestSpans("a")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	// The payload is sent even though it was not written.
+	if got := len(mockt.Spans()); got != 1 {
+		t.Errorf("expected 1 span sent, got %d", got)
+	}
+}
+
+func TestDebugSinkRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "payloads.json")
+
+	s, err := newDebugSink(path, DebugRotate(1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, signal := range []string{"1", "2", "3", "4"} {
+		p := DebugPayload{Time: time.Now(), Signal: signal, Payload: json.RawMessage("[]")}
+		if err := s.write(p); err != nil {
+			t.Fatalf("writing payload %s: %v", signal, err)
+		}
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each payload exceeds the maximum size so each is in its own file,
+	// and the oldest is removed.
+	for file, want := range map[string]string{
+		path:        "4",
+		path + ".1": "3",
+		path + ".2": "2",
+	} {
+		payloads := readDebugPayloads(t, file)
+		if len(payloads) != 1 || payloads[0].Signal != want {
+			t.Errorf("%s: got %v, want payload %s", file, payloads, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 rotated files: %v", err)
+	}
+}
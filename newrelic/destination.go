@@ -0,0 +1,156 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+)
+
+// DefaultDestination is the name of the destination of the API key passed to
+// NewExporterWithOptions.
+const DefaultDestination = "default"
+
+// Destination is a New Relic account or endpoint the Exporter sends data to
+// in addition to the default destination.
+type Destination struct {
+	// Name identifies the destination in the Exporter health. It must be
+	// unique.
+	Name string
+	// APIKey is the API key data is sent with.
+	APIKey string
+	// Options configure the harvesters of the destination, e.g. the
+	// endpoints of its region. They are applied after the options passed
+	// with WithTelemetryConfig. Every destination is harvested with the
+	// harvest period passed with WithTelemetryConfig.
+	Options []func(*telemetry.Config)
+}
+
+// DestinationHealth describes the outcome of the requests sent to a
+// destination.
+type DestinationHealth struct {
+	// Name is the name of the destination.
+	Name string
+	// Requests is the number of requests sent, including retries, and
+	// Failures the number of those that failed.
+	Requests int
+	Failures int
+	// ConsecutiveFailures is the number of requests that failed since the
+	// last successful request.
+	ConsecutiveFailures int
+	// LastSuccess and LastFailure are the times of the last successful and
+	// failed requests, if any.
+	LastSuccess time.Time
+	LastFailure time.Time
+	// LastError is the error of the last failed request.
+	LastError error
+}
+
+// Healthy returns true if the last request sent to the destination, if any,
+// succeeded.
+func (h DestinationHealth) Healthy() bool {
+	return h.ConsecutiveFailures == 0
+}
+
+// destinationHealth records the health of a destination.
+type destinationHealth struct {
+	lock   sync.Mutex
+	health DestinationHealth
+}
+
+// record records the outcome of a request. Requests fail with an error or a
+// status code other than 2xx.
+func (dh *destinationHealth) record(resp *http.Response, err error, now time.Time) {
+	if err == nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	dh.lock.Lock()
+	defer dh.lock.Unlock()
+	dh.health.Requests++
+	if err != nil {
+		dh.health.Failures++
+		dh.health.ConsecutiveFailures++
+		dh.health.LastFailure = now
+		dh.health.LastError = err
+		return
+	}
+	dh.health.ConsecutiveFailures = 0
+	dh.health.LastSuccess = now
+}
+
+func (dh *destinationHealth) get() DestinationHealth {
+	dh.lock.Lock()
+	defer dh.lock.Unlock()
+	return dh.health
+}
+
+// healthTransport records the outcome of the requests sent with base.
+type healthTransport struct {
+	base   http.RoundTripper
+	health *destinationHealth
+}
+
+func (t healthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	t.health.record(resp, err, time.Now())
+	return resp, err
+}
+
+// newDestination returns a destination whose harvesters are configured with
+// options. Payloads are written to the debug sink of the Exporter, if any,
+// and the requests sent are recorded in its health and the audit log of the
+// Exporter, if any. Requests the debug sink only writes are not recorded.
+func (e *Exporter) newDestination(name string, options []func(*telemetry.Config)) *destination {
+	options = options[:len(options):len(options)]
+	if e.audit != nil {
+		options = append(options, wrapTransport(func(base http.RoundTripper) http.RoundTripper {
+			return auditTransport{base: base, destination: name, logger: e.audit.logger, sensitive: e.audit.sensitive}
+		}))
+	}
+	health := &destinationHealth{health: DestinationHealth{Name: name}}
+	options = append(options, wrapTransport(func(base http.RoundTripper) http.RoundTripper {
+		return healthTransport{base: base, health: health}
+	}))
+	if e.debugSink != nil {
+		options = append(options, wrapTransport(func(base http.RoundTripper) http.RoundTripper {
+			return debugTransport{base: base, sink: e.debugSink}
+		}))
+	}
+	return &destination{
+		name:    name,
+		options: options,
+		health:  health,
+	}
+}
+
+// harvesterOptions returns the options of the harvesters of a destination
+// with apiKey: the Exporter options followed by options.
+func (e *Exporter) harvesterOptions(apiKey string, options ...func(*telemetry.Config)) []func(*telemetry.Config) {
+	tOpts := append([]func(*telemetry.Config){
+		func(cfg *telemetry.Config) {
+			cfg.Product = userAgentProduct
+			cfg.ProductVersion = version
+		},
+		telemetry.ConfigAPIKey(apiKey),
+	}, e.telemetryOptions...)
+	return append(tOpts, options...)
+}
+
+// Health returns the health of each destination of the Exporter, the default
+// destination first, followed by the destinations chosen by the Router.
+func (e *Exporter) Health() []DestinationHealth {
+	health := make([]DestinationHealth, 0, len(e.destinations))
+	for _, d := range e.destinations {
+		health = append(health, d.health.get())
+	}
+	if e.router != nil {
+		health = append(health, e.router.health()...)
+	}
+	return health
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/sdk/trace"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+// statusTransport responds to every request with a status code.
+type statusTransport struct {
+	lock     sync.Mutex
+	code     int
+	requests int
+}
+
+func (t *statusTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.requests++
+	return &http.Response{
+		StatusCode: t.code,
+		Body:       ioutil.NopCloser(&bytes.Buffer{}),
+	}, nil
+}
+
+func TestDestinations(t *testing.T) {
+	primary := &MockTransport{}
+	secondary := &MockTransport{}
+	failing := &statusTransport{code: http.StatusForbidden}
+	e := newTestExporter(t, "service", primary,
+		WithDestinations(
+			Destination{
+				Name:   "secondary",
+				APIKey: "secondaryKey",
+				Options: []func(*telemetry.Config){
+					func(cfg *telemetry.Config) { cfg.Client.Transport = secondary },
+				},
+			},
+			Destination{
+				Name:   "failing",
+				APIKey: "badKey",
+				Options: []func(*telemetry.Config){
+					func(cfg *telemetry.Config) {
+						cfg.HarvestTimeout = 0
+						cfg.Client.Transport = failing
+					},
+				},
+			},
+		),
+	)
+
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, newTestSpans("a", "b")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	for name, mockt := range map[string]*MockTransport{"primary": primary, "secondary": secondary} {
+		if got := len(mockt.Spans()); got != 2 {
+			t.Errorf("%s destination: expected 2 spans, got %d", name, got)
+		}
+	}
+
+	health := e.Health()
+	if len(health) != 3 {
+		t.Fatalf("expected the health of 3 destinations, got %d", len(health))
+	}
+	for i, want := range []struct {
+		name    string
+		healthy bool
+	}{
+		{name: DefaultDestination, healthy: true},
+		{name: "secondary", healthy: true},
+		{name: "failing", healthy: false},
+	} {
+		h := health[i]
+		if h.Name != want.name {
+			t.Errorf("destination %d: got name %q, want %q", i, h.Name, want.name)
+		}
+		if h.Healthy() != want.healthy {
+			t.Errorf("%s destination: got healthy %v, want %v (%+v)", h.Name, h.Healthy(), want.healthy, h)
+		}
+		if h.Requests == 0 {
+			t.Errorf("%s destination: no requests recorded", h.Name)
+		}
+	}
+	if h := health[2]; h.LastError == nil || h.Failures != h.Requests || h.LastFailure.IsZero() {
+		t.Errorf("failing destination: unexpected health %+v", h)
+	}
+	if h := health[0]; h.LastSuccess.IsZero() || h.Failures != 0 {
+		t.Errorf("default destination: unexpected health %+v", h)
+	}
+}
+
+func TestDestinationsTransformOnce(t *testing.T) {
+	primary := &MockTransport{}
+	secondary := &MockTransport{}
+	e := newTestExporter(t, "service", primary,
+		WithTransactions(0),
+		WithDestinations(Destination{
+			Name:   "secondary",
+			APIKey: "secondaryKey",
+			Options: []func(*telemetry.Config){
+				func(cfg *telemetry.Config) { cfg.Client.Transport = secondary },
+			},
+		}),
+	)
+
+	spans := newTestSpans("root")
+	spans[0].SpanKind = apitrace.SpanKindServer
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, []*trace.SpanSnapshot{spans[0]}); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	for name, mockt := range map[string]*MockTransport{"primary": primary, "secondary": secondary} {
+		if got := len(mockt.Spans()); got != 1 {
+			t.Errorf("%s destination: expected 1 span, got %d", name, got)
+		}
+		if got := len(mockt.Events); got != 1 {
+			t.Errorf("%s destination: expected 1 transaction event, got %d", name, got)
+		}
+		if got := len(mockt.Metrics()); got == 0 {
+			t.Errorf("%s destination: expected transaction metrics", name)
+		}
+	}
+}
+
+func TestDestinationsInvalidName(t *testing.T) {
+	for _, name := range []string{"", DefaultDestination} {
+		_, err := NewExporterWithOptions("service", "apiKey", WithDestinations(Destination{Name: name, APIKey: "key"}))
+		if err == nil {
+			t.Errorf("expected error for destination name %q", name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/transform"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxEventTypeLength is the maximum length of a New Relic event type.
+const maxEventTypeLength = 255
+
+// eventTypeRegexp matches the characters allowed in a New Relic event type.
+var eventTypeRegexp = regexp.MustCompile(`^[a-zA-Z0-9:_ ]+$`)
+
+// ErrInvalidEventType is returned when a custom event is recorded with an
+// event type New Relic does not accept.
+var ErrInvalidEventType = errors.New("invalid event type")
+
+// validateEventType returns an error if eventType is not a valid New Relic
+// event type. Event types must be at most 255 characters long and contain
+// only alphanumeric characters, underscores, colons, and spaces.
+func validateEventType(eventType string) error {
+	if eventType == "" || len(eventType) > maxEventTypeLength || !eventTypeRegexp.MatchString(eventType) {
+		return fmt.Errorf("%w: %q", ErrInvalidEventType, eventType)
+	}
+	return nil
+}
+
+// RecordEvent records a custom event of eventType to be sent to the New Relic
+// Event API, e.g. `CheckoutCompleted`. The event includes the Resource and
+// service attributes of the Exporter and is linked to the span active in
+// ctx, if any, with `trace.id` and `span.id` attributes.
+//
+// Events are sent with the same batching, retries, and shutdown behavior as
+// spans and metrics. An ErrInvalidEventType error is returned if eventType is
+// not accepted by New Relic.
+func (e *Exporter) RecordEvent(ctx context.Context, eventType string, attrs ...attribute.KeyValue) error {
+	if nil == e {
+		return nil
+	}
+	if err := validateEventType(eventType); err != nil {
+		return err
+	}
+
+	e.recording.RLock()
+	defer e.recording.RUnlock()
+	h, err := e.routedHarvesterFor(e.resource, attrs)
+	if err != nil {
+		return err
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	event := transform.Event(eventType, time.Now(), attrs, e.commonAttributes(e.resource), sc)
+	return h.RecordEvent(event)
+}
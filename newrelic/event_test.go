@@ -0,0 +1,68 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestValidateEventType(t *testing.T) {
+	for _, eventType := range []string{"CheckoutCompleted", "checkout_completed", "Checkout:Completed", "Checkout Completed", "Checkout2"} {
+		if err := validateEventType(eventType); err != nil {
+			t.Errorf("%q: unexpected error: %v", eventType, err)
+		}
+	}
+	for _, eventType := range []string{"", "Checkout-Completed", "Checkout.Completed", strings.Repeat("a", 256)} {
+		if err := validateEventType(eventType); !errors.Is(err, ErrInvalidEventType) {
+			t.Errorf("%q: got %v, want %v", eventType, err, ErrInvalidEventType)
+		}
+	}
+}
+
+func TestRecordEvent(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "", mockt,
+		WithResource(resource.NewWithAttributes(
+			semconv.ServiceNameKey.String("event-service"),
+			semconv.HostNameKey.String("host"),
+		)),
+	)
+
+	ctx := apitrace.ContextWithSpanContext(context.Background(), testSpanContext())
+	if err := e.RecordEvent(ctx, "CheckoutCompleted", attribute.Float64("total", 9.99)); err != nil {
+		t.Fatalf("recording event: %v", err)
+	}
+	if err := e.RecordEvent(ctx, "Checkout-Completed"); !errors.Is(err, ErrInvalidEventType) {
+		t.Errorf("recording invalid event: got %v, want %v", err, ErrInvalidEventType)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	if got := len(mockt.Events); got != 1 {
+		t.Fatalf("expecting 1 event, got %d", got)
+	}
+	want := map[string]interface{}{
+		"eventType":    "CheckoutCompleted",
+		"total":        9.99,
+		"service.name": "event-service",
+		"entity.name":  "event-service",
+		"host.name":    "host",
+		"trace.id":     testTraceID.String(),
+		"span.id":      testSpanID.String(),
+	}
+	for k, v := range want {
+		if got := mockt.Events[0][k]; got != v {
+			t.Errorf("event attribute %s: got %v, want %v", k, got, v)
+		}
+	}
+}
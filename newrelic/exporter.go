@@ -10,16 +10,19 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/global"
 	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+	"go.opentelemetry.io/otel/sdk/resource"
 	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
 	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
 	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
-	"go.opentelemetry.io/otel/sdk/resource"
-	"go.opentelemetry.io/otel/semconv"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
@@ -33,37 +36,180 @@ const (
 	userAgentProduct = "NewRelic-Go-OpenTelemetry"
 )
 
+// Names of the exporter self-metrics.
+const (
+	droppedAttributesMetricName = "newrelic.exporter.span.dropped_attributes"
+	droppedEventsMetricName     = "newrelic.exporter.span.dropped_events"
+	droppedLinksMetricName      = "newrelic.exporter.span.dropped_links"
+)
+
 // Exporter exports OpenTelemetry data to New Relic.
 type Exporter struct {
-	harvester *telemetry.Harvester
-	// serviceName is the name of this service or application.
+	// telemetryOptions configure the harvesters of every destination.
+	telemetryOptions []func(*telemetry.Config)
+	// debugSink is written the payloads sent to New Relic when not nil.
+	debugSink *debugSink
+	// audit records the requests sent to New Relic when not nil.
+	audit *audit
+	// destinations are the New Relic accounts or endpoints data is sent
+	// to, the default destination first.
+	destinations []*destination
+	// router sends data to the destinations chosen by a Router when not
+	// nil.
+	router *router
+	// lock protects the harvesters of the destinations.
+	lock sync.Mutex
+	// recording is read locked from the lookup of a harvester until the
+	// data is recorded with it, and write locked while the harvesters to
+	// send are collected. Evicted harvesters are therefore harvested only
+	// once no data is being recorded with them.
+	recording sync.RWMutex
+	// maxResources is the maximum number of Resources each destination
+	// keeps a harvester for, and resourceIdleTimeout how long they are
+	// kept unused.
+	maxResources        int
+	resourceIdleTimeout time.Duration
+
+	// stop stops the periodic harvests, done is closed once they are
+	// stopped.
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	// shutdownOnce shuts the Exporter down once, shutdownErr is the
+	// result.
+	shutdownOnce sync.Once
+	shutdownErr  error
+
+	// serviceName is the name of this service or application used when the
+	// Resource of the exported data does not contain one.
 	serviceName string
+	// selfMetrics reports exporter self-metrics when true.
+	selfMetrics bool
+	// spanMetrics derives metrics from spans when not nil.
+	spanMetrics *spanMetrics
+	// resource describes the entity producing recorded logs and events.
+	resource *resource.Resource
+	// semconv translates span attributes and metric labels when not nil.
+	semconv *transform.SemconvTranslator
+	// transactions maps entry spans to APM transactions when not nil.
+	transactions *transactions
+	// traceObserver streams spans to Infinite Tracing when not nil.
+	traceObserver *traceObserver
+	// otlp sends spans and metrics with OTLP when not nil.
+	otlp *otlp.Exporter
+	// validator checks spans and metrics before they are recorded when not
+	// nil.
+	validator *transform.Validator
+	// dropSkewedSpans drops spans that end before they start when true.
+	dropSkewedSpans bool
+	// metricNormalizer normalizes the names and units of metrics when not
+	// nil.
+	metricNormalizer *transform.MetricNormalizer
 }
 
-var (
-	errServiceNameEmpty = errors.New("service name is required")
-)
-
 // NewExporter creates a new Exporter that exports telemetry to New Relic.
+//
+// The service name of the exported data is taken from the `service.name`
+// attribute of its Resource. The service argument is only used as a fallback
+// for data whose Resource has no service name and may be empty. Data is sent
+// to New Relic in separate payloads for each Resource, so a single Exporter
+// can export data for many services.
 func NewExporter(service, apiKey string, options ...func(*telemetry.Config)) (*Exporter, error) {
-	if service == "" {
-		return nil, errServiceNameEmpty
+	return NewExporterWithOptions(service, apiKey, WithTelemetryConfig(options...))
+}
+
+// NewExporterWithOptions creates a new Exporter that exports telemetry to New
+// Relic configured with options.
+func NewExporterWithOptions(service, apiKey string, options ...Option) (_ *Exporter, err error) {
+	cfg := newConfig(options...)
+	if cfg.router != nil && (cfg.otlp || cfg.traceObserverHost != "") {
+		return nil, errors.New("a router cannot be used with OTLP or Infinite Tracing")
 	}
-	options = append([]func(*telemetry.Config){
-		func(cfg *telemetry.Config) {
-			cfg.Product = userAgentProduct
-			cfg.ProductVersion = version
-		},
-		telemetry.ConfigAPIKey(apiKey),
-	}, options...)
-	h, err := telemetry.NewHarvester(options...)
-	if nil != err {
-		return nil, err
+	e := &Exporter{
+		telemetryOptions: cfg.telemetryOptions,
+		serviceName:      service,
+		selfMetrics:      cfg.selfMetrics,
+		resource:         cfg.resource,
+		validator:        newValidator(cfg.validation),
+		dropSkewedSpans:  cfg.dropSkewedSpans,
+		metricNormalizer: cfg.metricNormalizer,
+
+		maxResources:        defaultMaxResources,
+		resourceIdleTimeout: defaultResourceIdleTimeout,
+		stop:                make(chan struct{}),
+		done:                make(chan struct{}),
 	}
-	return &Exporter{
-		harvester:   h,
-		serviceName: service,
-	}, nil
+	defer func() {
+		// Close what was opened if the Exporter is not returned.
+		if err != nil {
+			e.close()
+		}
+	}()
+	if cfg.debugPath != "" {
+		s, err := newDebugSink(cfg.debugPath, cfg.debugOptions...)
+		if err != nil {
+			return nil, err
+		}
+		e.debugSink = s
+	}
+	if cfg.auditLogger != nil {
+		e.audit = &audit{logger: cfg.auditLogger, sensitive: make(map[string]bool, len(cfg.auditSensitive))}
+		for _, k := range cfg.auditSensitive {
+			e.audit.sensitive[k] = true
+		}
+	}
+	e.destinations = []*destination{e.newDestination(DefaultDestination, e.harvesterOptions(apiKey))}
+	names := map[string]bool{DefaultDestination: true}
+	for _, d := range cfg.destinations {
+		if d.Name == "" || names[d.Name] {
+			return nil, fmt.Errorf("invalid destination name: %q", d.Name)
+		}
+		names[d.Name] = true
+		e.destinations = append(e.destinations, e.newDestination(d.Name, e.harvesterOptions(d.APIKey, d.Options...)))
+	}
+	if cfg.spanMetricsNames > 0 {
+		e.spanMetrics = newSpanMetrics(cfg.spanMetricsNames)
+	}
+	if cfg.transactions {
+		e.transactions = &transactions{apdexT: cfg.apdexT}
+		if e.transactions.apdexT <= 0 {
+			e.transactions.apdexT = defaultApdexThreshold
+		}
+	}
+	if cfg.semconvTarget != "" {
+		t, err := transform.NewSemconvTranslator(cfg.semconvTarget)
+		if err != nil {
+			return nil, err
+		}
+		e.semconv = t
+	}
+	for _, d := range e.destinations {
+		h, err := e.newHarvester(d, nil)
+		if nil != err {
+			return nil, err
+		}
+		d.harvester = h
+	}
+	if cfg.traceObserverHost != "" {
+		o, err := newTraceObserver(cfg.traceObserverHost, apiKey, cfg.traceObserverQueueSize, cfg.traceObserverDialOptions...)
+		if err != nil {
+			return nil, err
+		}
+		e.traceObserver = o
+	}
+	if cfg.otlp {
+		o, err := newOTLPExporter(apiKey, cfg.otlpOptions...)
+		if err != nil {
+			return nil, err
+		}
+		e.otlp = o
+	}
+	if cfg.router != nil {
+		e.router = newRouter(cfg.router, cfg.maxRoutedDestinations, cfg.routedIdleTimeout)
+	}
+	go e.run(harvestPeriod(cfg.telemetryOptions))
+	return e, nil
 }
 
 // NewExportPipeline creates a new OpenTelemetry telemetry pipeline using a
@@ -75,6 +221,8 @@ func NewExporter(service, apiKey string, options ...func(*telemetry.Config)) (*E
 //    * `NEW_RELIC_API_KEY`: New Relic Event API key.
 //    * `NEW_RELIC_METRIC_URL`: Override URL to New Relic metric endpoint.
 //    * `NEW_RELIC_TRACE_URL`: Override URL to New Relic trace endpoint.
+//    * `NEW_RELIC_LOG_URL`: Override URL to New Relic log endpoint.
+//    * `NEW_RELIC_EVENT_URL`: Override URL to New Relic event endpoint.
 //
 // More information about the New Relic Event API key can be found
 // here: https://docs.newrelic.com/docs/apis/get-started/intro-apis/types-new-relic-api-keys#event-insert-key.
@@ -86,7 +234,11 @@ func NewExporter(service, apiKey string, options ...func(*telemetry.Config)) (*E
 //
 //    * EU metric API endpoint: metric-api.eu.newrelic.com/metric/v1
 //    * EU trace API endpoint: trace-api.eu.newrelic.com/trace/v1
-func NewExportPipeline(service string, traceOpt []sdktrace.TracerProviderOption, cOpt []controller.Option) (trace.TracerProvider, *controller.Controller, error) {
+//
+// The pipeline Resource is built from the detectors configured with
+// WithResourceDetectors, the service name, and the `OTEL_RESOURCE_ATTRIBUTES`
+// environment variable.
+func NewExportPipeline(service string, traceOpt []sdktrace.TracerProviderOption, cOpt []controller.Option, options ...Option) (trace.TracerProvider, *controller.Controller, error) {
 	apiKey, ok := os.LookupEnv("NEW_RELIC_API_KEY")
 	if !ok {
 		return nil, nil, errors.New("missing New Relic API key")
@@ -101,16 +253,25 @@ func NewExportPipeline(service string, traceOpt []sdktrace.TracerProviderOption,
 	if u, ok := os.LookupEnv("NEW_RELIC_TRACE_URL"); ok {
 		eOpts = append(eOpts, telemetry.ConfigSpansURLOverride(u))
 	}
+	if u, ok := os.LookupEnv("NEW_RELIC_LOG_URL"); ok {
+		eOpts = append(eOpts, telemetry.ConfigLogsURLOverride(u))
+	}
+	if u, ok := os.LookupEnv("NEW_RELIC_EVENT_URL"); ok {
+		eOpts = append(eOpts, telemetry.ConfigEventsURLOverride(u))
+	}
+
+	// Default resource with the detected attributes and service name. This
+	// is overwritten if another is passed in traceOpt or pushOpt.
+	ctx, cancel := context.WithTimeout(context.Background(), resourceDetectionTimeout)
+	r := pipelineResource(ctx, service, newConfig(options...))
+	cancel()
 
-	exporter, err := NewExporter(service, apiKey, eOpts...)
+	options = append([]Option{WithTelemetryConfig(eOpts...), WithResource(r)}, options...)
+	exporter, err := NewExporterWithOptions(service, apiKey, options...)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Minimally default resource with a service name. This is overwritten if
-	// another is passed in traceOpt or pushOpt.
-	r := resource.NewWithAttributes(semconv.ServiceNameKey.String(service))
-
 	tp := sdktrace.NewTracerProvider(
 		append([]sdktrace.TracerProviderOption{
 			sdktrace.WithSyncer(exporter),
@@ -142,6 +303,8 @@ func NewExportPipeline(service string, traceOpt []sdktrace.TracerProviderOption,
 //    * `NEW_RELIC_API_KEY`: New Relic Insights insert key.
 //    * `NEW_RELIC_METRIC_URL`: Override URL to New Relic metric endpoint.
 //    * `NEW_RELIC_TRACE_URL`: Override URL to New Relic trace endpoint.
+//    * `NEW_RELIC_LOG_URL`: Override URL to New Relic log endpoint.
+//    * `NEW_RELIC_EVENT_URL`: Override URL to New Relic event endpoint.
 // The exporter will send telemetry to the default New Relic metric and trace
 // API endpoints in the United States:
 // * Traces: https://trace-api.newrelic.com/trace/v1
@@ -150,8 +313,8 @@ func NewExportPipeline(service string, traceOpt []sdktrace.TracerProviderOption,
 // to send data to our EU endpoints or to set up Infinite Tracing.
 // For information about changing endpoints, see [OpenTelemetry: Advanced configuration](https://docs.newrelic.com/docs/integrations/open-source-telemetry-integrations/opentelemetry/opentelemetry-advanced-configuration#h2-change-endpoints).
 
-func InstallNewPipeline(service string) (*controller.Controller, error) {
-	tp, controller, err := NewExportPipeline(service, nil, nil)
+func InstallNewPipeline(service string, options ...Option) (*controller.Controller, error) {
+	tp, controller, err := NewExportPipeline(service, nil, nil, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -173,8 +336,74 @@ func (e *Exporter) ExportSpans(ctx context.Context, spans []*sdktrace.SpanSnapsh
 	}
 
 	var errs []string
+	var otlpSpans []*sdktrace.SpanSnapshot
+	e.recording.RLock()
 	for _, s := range spans {
-		if err := e.harvester.RecordSpan(transform.Span(e.serviceName, s)); err != nil {
+		if err := transform.CheckSpan(s); err != nil {
+			otel.Handle(err)
+			if e.dropSkewedSpans || !errors.Is(err, transform.ErrClockSkew) {
+				continue
+			}
+			// Repair a copy so the data derived from the span and the
+			// span sent with OTLP have no negative duration either.
+			s = transform.RepairClockSkew(s)
+		}
+		if e.semconv != nil {
+			// Translate a copy, the snapshot is shared with other
+			// span processors.
+			translated := *s
+			translated.Attributes = e.semconv.Translate(s.Attributes)
+			s = &translated
+		}
+		otlpOnly := e.otlp != nil && e.traceObserver == nil
+		if otlpOnly {
+			otlpSpans = append(otlpSpans, e.otlpSpan(s))
+			if !e.derivesFromSpans() {
+				continue
+			}
+		}
+		res := serviceResource(s.Resource, s.Attributes)
+		h, err := e.routedHarvesterFor(res, s.Attributes)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		span := transform.Span(e.serviceName, s)
+		// Validation only applies to spans sent to the New Relic APIs.
+		if e.validator != nil && !otlpOnly {
+			v, err := e.validator.Span(&span)
+			recordValidation(h, span.ServiceName, "span", v, err != nil)
+			if err != nil {
+				if err := e.invalidError(err); err != nil {
+					errs = append(errs, err.Error())
+				}
+				continue
+			}
+		}
+		if e.transactions != nil {
+			if err := e.transactions.record(h, &span, s, e.commonAttributes(res)); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		switch {
+		case e.traceObserver != nil:
+			err = e.traceObserver.send(transform.StreamSpan(span, e.commonAttributes(res)))
+		case otlpOnly:
+			// Exported with OTLP below.
+		default:
+			err = h.RecordSpan(span)
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		if e.selfMetrics {
+			recordDropped(h, span.ServiceName, s)
+		}
+		e.spanMetrics.record(h, span, s)
+	}
+	e.recording.RUnlock()
+	if len(otlpSpans) > 0 {
+		if err := e.otlp.ExportSpans(ctx, otlpSpans); err != nil {
 			errs = append(errs, err.Error())
 		}
 	}
@@ -185,23 +414,118 @@ func (e *Exporter) ExportSpans(ctx context.Context, spans []*sdktrace.SpanSnapsh
 	return nil
 }
 
+// derivesFromSpans returns true if the Exporter records data derived from
+// spans with the harvesters of their destination.
+func (e *Exporter) derivesFromSpans() bool {
+	return e.transactions != nil || e.spanMetrics != nil || e.selfMetrics
+}
+
+// recordDropped aggregates the number of span attributes, events, and links
+// the OpenTelemetry SDK dropped from span into exporter self-metrics.
+func recordDropped(hs harvesters, service string, span *sdktrace.SpanSnapshot) {
+	attrs := map[string]interface{}{"service.name": service}
+	hs.aggregate(func(agg *telemetry.MetricAggregator) {
+		if span.DroppedAttributeCount > 0 {
+			agg.Count(droppedAttributesMetricName, attrs).Increase(float64(span.DroppedAttributeCount))
+		}
+		if span.DroppedMessageEventCount > 0 {
+			agg.Count(droppedEventsMetricName, attrs).Increase(float64(span.DroppedMessageEventCount))
+		}
+		if span.DroppedLinkCount > 0 {
+			agg.Count(droppedLinksMetricName, attrs).Increase(float64(span.DroppedLinkCount))
+		}
+	})
+}
+
 // Export exports metrics to New Relic.
-func (e *Exporter) Export(_ context.Context, cps exportmetric.CheckpointSet) error {
-	return cps.ForEach(e, func(record exportmetric.Record) error {
+func (e *Exporter) Export(ctx context.Context, cps exportmetric.CheckpointSet) error {
+	if e.otlp != nil {
+		return e.otlp.Export(ctx, otlpCheckpointSet{CheckpointSet: cps, transform: e.otlpRecord})
+	}
+	var errs []string
+	e.recording.RLock()
+	defer e.recording.RUnlock()
+	err := cps.ForEach(e, func(record exportmetric.Record) error {
+		if e.semconv != nil {
+			labels := attribute.NewSet(e.semconv.Translate(record.Labels().ToSlice())...)
+			record = exportmetric.NewRecord(record.Descriptor(), &labels, record.Resource(), record.Aggregation(), record.StartTime(), record.EndTime())
+		}
+		labels := record.Labels().ToSlice()
+		res := serviceResource(record.Resource(), labels)
+		h, err := e.routedHarvesterFor(res, labels)
+		if err != nil {
+			return err
+		}
 		m, err := transform.Record(e.serviceName, record)
 		if err != nil {
 			return err
 		}
-		e.harvester.RecordMetric(m)
+		m = e.metricNormalizer.Normalize(m, string(record.Descriptor().Unit()))
+		if e.validator != nil {
+			var v transform.Validation
+			m, v, err = e.validator.Metric(m)
+			recordValidation(h, transform.ResolveService(e.serviceName, res).Name, "metric", v, err != nil)
+			if err != nil {
+				// Invalid metrics do not stop the export of the others.
+				if err := e.invalidError(err); err != nil {
+					errs = append(errs, err.Error())
+				}
+				return nil
+			}
+		}
+		h.RecordMetric(m)
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	return joinErrors(errs)
+}
+
+// close closes the connections and files of e without sending the data it
+// holds.
+func (e *Exporter) close() {
+	if e.traceObserver != nil {
+		e.traceObserver.close(context.Background())
+	}
+	if e.otlp != nil {
+		e.otlp.Shutdown(context.Background())
+	}
+	if e.debugSink != nil {
+		e.debugSink.close()
+	}
 }
 
 func (e *Exporter) ExportKindFor(_ *metric.Descriptor, _ aggregation.Kind) exportmetric.ExportKind {
 	return exportmetric.DeltaExportKind
 }
 
+// Shutdown sends the data held by the Exporter and releases its resources.
+// Only the first call shuts the Exporter down, later calls return its
+// result, so an Exporter used by both a TailSamplingProcessor and a
+// TracerProvider can be shut down by each.
 func (e *Exporter) Shutdown(ctx context.Context) error {
-	e.harvester.HarvestNow(ctx)
-	return nil
+	e.shutdownOnce.Do(func() { e.shutdownErr = e.shutdown(ctx) })
+	return e.shutdownErr
+}
+
+// shutdown shuts the Exporter down.
+func (e *Exporter) shutdown(ctx context.Context) error {
+	var err error
+	if e.traceObserver != nil {
+		err = e.traceObserver.close(ctx)
+	}
+	e.stopHarvests()
+	if e.otlp != nil {
+		if oErr := e.otlp.Shutdown(ctx); err == nil {
+			err = oErr
+		}
+	}
+	e.harvestNow(ctx)
+	if e.debugSink != nil {
+		if dErr := e.debugSink.close(); err == nil {
+			err = dErr
+		}
+	}
+	return err
 }
@@ -13,6 +13,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -20,20 +22,23 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/number"
+	exportmetric "go.opentelemetry.io/otel/sdk/export/metric"
+	sumAgg "go.opentelemetry.io/otel/sdk/metric/aggregator/sum"
 	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
 	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
 	selector "go.opentelemetry.io/otel/sdk/metric/selector/simple"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/semconv"
+	apitrace "go.opentelemetry.io/otel/trace"
 )
 
-func TestServiceNameMissing(t *testing.T) {
-	e, err := NewExporter("", "apiKey")
-	if e != nil {
-		t.Error(e)
+func TestServiceNameOptional(t *testing.T) {
+	e, err := NewExporter("", "apiKey", telemetry.ConfigHarvestPeriod(0))
+	if e == nil {
+		t.Error("expected exporter without a service name")
 	}
-	if err != errServiceNameEmpty {
+	if err != nil {
 		t.Error(err)
 	}
 }
@@ -47,7 +52,8 @@ func TestNilExporter(t *testing.T) {
 
 // MockTransport caches decompressed request bodies
 type MockTransport struct {
-	Data []Data
+	Data   []Data
+	Events []map[string]interface{}
 }
 
 func (c *MockTransport) Spans() []Span {
@@ -58,6 +64,22 @@ func (c *MockTransport) Spans() []Span {
 	return spans
 }
 
+func (c *MockTransport) Commons() []Common {
+	var commons []Common
+	for _, data := range c.Data {
+		commons = append(commons, data.Common)
+	}
+	return commons
+}
+
+func (c *MockTransport) Logs() []Log {
+	var logs []Log
+	for _, data := range c.Data {
+		logs = append(logs, data.Logs...)
+	}
+	return logs
+}
+
 func (c *MockTransport) Metrics() []Metric {
 	var metrics []Metric
 	for _, data := range c.Data {
@@ -94,6 +116,18 @@ func (c *MockTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 }
 
 func (c *MockTransport) ParseRequest(b []byte) error {
+	// Event API payloads are a flat list of events.
+	var events []map[string]interface{}
+	if err := json.Unmarshal(b, &events); err != nil {
+		return err
+	}
+	if len(events) > 0 {
+		if _, ok := events[0]["eventType"]; ok {
+			c.Events = append(c.Events, events...)
+			return nil
+		}
+	}
+
 	var data []Data
 	if err := json.Unmarshal(b, &data); err != nil {
 		return err
@@ -106,13 +140,14 @@ type Data struct {
 	Common  Common                 `json:"common"`
 	Spans   []Span                 `json:"spans"`
 	Metrics []Metric               `json:"metrics"`
+	Logs    []Log                  `json:"logs"`
 	XXX     map[string]interface{} `json:"-"`
 }
 
 type Common struct {
 	timestamp  interface{}
 	interval   interface{}
-	Attributes map[string]string `json:"attributes"`
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
 type Span struct {
@@ -122,6 +157,12 @@ type Span struct {
 	timestamp  interface{}
 }
 
+type Log struct {
+	Message    string                 `json:"message"`
+	Attributes map[string]interface{} `json:"attributes"`
+	timestamp  interface{}
+}
+
 type Metric struct {
 	Name       string      `json:"name"`
 	Type       string      `json:"type"`
@@ -130,6 +171,45 @@ type Metric struct {
 	Attributes map[string]interface{} `json:"attributes"`
 }
 
+// testAPIKey is the API key of the Exporters created by newTestExporter.
+const testAPIKey = "apiKey"
+
+// testTraceID and testSpanID are the IDs of the spans created by tests.
+var (
+	testTraceID, _ = apitrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	testSpanID, _  = apitrace.SpanIDFromHex("00f067aa0ba902b7")
+)
+
+// testSpanContext returns the span context of testTraceID and testSpanID.
+func testSpanContext() apitrace.SpanContext {
+	return apitrace.NewSpanContext(apitrace.SpanContextConfig{
+		TraceID: testTraceID,
+		SpanID:  testSpanID,
+	})
+}
+
+// newTestExporter returns an Exporter of service, configured with options,
+// that sends all its data to transport. Data is only sent when the Exporter
+// is harvested or shut down.
+func newTestExporter(t *testing.T, service string, transport http.RoundTripper, options ...Option) *Exporter {
+	t.Helper()
+	options = append([]Option{WithTelemetryConfig(
+		telemetry.ConfigHarvestPeriod(0),
+		func(cfg *telemetry.Config) {
+			cfg.SpansURLOverride = "localhost"
+			cfg.MetricsURLOverride = "localhost"
+			cfg.EventsURLOverride = "localhost"
+			cfg.LogsURLOverride = "localhost"
+			cfg.Client.Transport = transport
+		},
+	)}, options...)
+	e, err := NewExporterWithOptions(service, testAPIKey, options...)
+	if err != nil {
+		t.Fatalf("failed to instantiate exporter: %v", err)
+	}
+	return e
+}
+
 func TestEndToEndTracer(t *testing.T) {
 	numSpans := 4
 	serviceName := "opentelemetry-service"
@@ -176,13 +256,19 @@ func TestEndToEndTracer(t *testing.T) {
 
 	// Wait >2 cycles.
 	<-time.After(40 * time.Millisecond)
-	e.harvester.HarvestNow(context.Background())
+	e.harvestNow(context.Background())
 
 	gotSpans := mockt.Spans()
 	if got := len(gotSpans); got != numSpans {
 		t.Fatalf("expecting %d spans, got %d", numSpans, got)
 	}
 
+	for _, c := range mockt.Commons() {
+		if got := c.Attributes["service.name"]; got != serviceName {
+			t.Errorf("common service name: got %q, want %q", got, serviceName)
+		}
+	}
+
 	var traceID, parentID string
 	// Reverse order to start at the beginning of the trace.
 	for i := len(gotSpans) - 1; i >= 0; i-- {
@@ -408,3 +494,413 @@ func TestEndToEndMeter(t *testing.T) {
 		}
 	}
 }
+
+func TestSelfMetricsDropped(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "opentelemetry-service", mockt, WithSelfMetrics())
+
+	span := &trace.SpanSnapshot{
+		SpanContext:              testSpanContext(),
+		Name:                     "span",
+		DroppedAttributeCount:    1,
+		DroppedMessageEventCount: 2,
+	}
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, []*trace.SpanSnapshot{span, span}); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	want := map[string]float64{
+		droppedAttributesMetricName: 2,
+		droppedEventsMetricName:     4,
+	}
+	got := make(map[string]float64)
+	for _, m := range mockt.Metrics() {
+		got[m.Name] = m.Value.(float64)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("self-metrics: got %v, want %v", got, want)
+	}
+}
+
+func TestResourceCommonAttributes(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "opentelemetry-service", mockt)
+
+	newSpan := func(host string) *trace.SpanSnapshot {
+		return &trace.SpanSnapshot{
+			SpanContext: testSpanContext(),
+			Name:        "span",
+			Resource:    resource.NewWithAttributes(semconv.HostNameKey.String(host)),
+		}
+	}
+	ctx := context.Background()
+	spans := []*trace.SpanSnapshot{newSpan("a"), newSpan("b"), newSpan("a")}
+	if err := e.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	if got := len(mockt.Data); got != 2 {
+		t.Fatalf("expecting 2 payloads, got %d", got)
+	}
+	spansPerHost := make(map[interface{}]int)
+	for _, d := range mockt.Data {
+		host := d.Common.Attributes[string(semconv.HostNameKey)]
+		spansPerHost[host] += len(d.Spans)
+		for _, s := range d.Spans {
+			if _, ok := s.Attributes[string(semconv.HostNameKey)]; ok {
+				t.Errorf("resource attribute repeated in span %s", s.ID)
+			}
+		}
+	}
+	if want := map[interface{}]int{"a": 2, "b": 1}; !reflect.DeepEqual(spansPerHost, want) {
+		t.Errorf("spans per resource: got %v, want %v", spansPerHost, want)
+	}
+}
+
+func TestResourceHarvesterEviction(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "opentelemetry-service", mockt)
+	e.maxResources = 2
+
+	newSpan := func(host string) *trace.SpanSnapshot {
+		return &trace.SpanSnapshot{
+			SpanContext: testSpanContext(),
+			Name:        "span",
+			Resource:    resource.NewWithAttributes(semconv.HostNameKey.String(host)),
+		}
+	}
+	ctx := context.Background()
+	for _, host := range []string{"a", "b", "c"} {
+		if err := e.ExportSpans(ctx, []*trace.SpanSnapshot{newSpan(host)}); err != nil {
+			t.Fatalf("exporting spans: %v", err)
+		}
+	}
+	d := e.destinations[0]
+	if got := len(d.harvesters); got != 2 {
+		t.Errorf("expected 2 resource harvesters, got %d", got)
+	}
+	if got := len(d.evicted); got != 1 {
+		t.Errorf("expected 1 evicted harvester, got %d", got)
+	}
+
+	// The evicted harvester is sent its data, and idle harvesters are
+	// evicted.
+	e.harvestAt(ctx, time.Now().Add(2*defaultResourceIdleTimeout))
+	if got := len(mockt.Spans()); got != 3 {
+		t.Errorf("expected 3 spans, got %d", got)
+	}
+	if got := len(d.harvesters) + len(d.evicted); got != 0 {
+		t.Errorf("expected no resource harvesters, got %d", got)
+	}
+}
+
+func TestServiceNamePerResource(t *testing.T) {
+	fallback := "fallback-service"
+	mockt := &MockTransport{}
+	e := newTestExporter(t, fallback, mockt)
+
+	newSpan := func(res *resource.Resource, attrs ...attribute.KeyValue) *trace.SpanSnapshot {
+		return &trace.SpanSnapshot{
+			SpanContext: testSpanContext(),
+			Name:        "span",
+			Resource:    res,
+			Attributes:  attrs,
+		}
+	}
+	ctx := context.Background()
+	spans := []*trace.SpanSnapshot{
+		// The Resource service name is authoritative.
+		newSpan(resource.NewWithAttributes(semconv.ServiceNameKey.String("service-a")), semconv.ServiceNameKey.String("ignored")),
+		newSpan(resource.NewWithAttributes(semconv.ServiceNameKey.String("service-b"))),
+		// The span attribute is used if the Resource has none.
+		newSpan(resource.NewWithAttributes(semconv.HostNameKey.String("host")), semconv.ServiceNameKey.String("attribute-service")),
+		// The exporter service name is used if neither has one.
+		newSpan(resource.NewWithAttributes(semconv.HostNameKey.String("host"))),
+	}
+	if err := e.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	got := make(map[interface{}]interface{})
+	for _, d := range mockt.Data {
+		if len(d.Spans) != 1 {
+			t.Fatalf("expecting 1 span per payload, got %d", len(d.Spans))
+		}
+		if got, want := d.Common.Attributes["entity.name"], d.Common.Attributes["service.name"]; got != want {
+			t.Errorf("common entity name: got %q, want %q", got, want)
+		}
+		got[d.Common.Attributes["service.name"]] = d.Spans[0].Attributes["service.name"]
+	}
+	want := map[interface{}]interface{}{
+		"service-a":         "service-a",
+		"service-b":         "service-b",
+		"attribute-service": "attribute-service",
+		fallback:            fallback,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("service names: got %v, want %v", got, want)
+	}
+}
+
+func TestServiceNameMetricLabel(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "fallback-service", mockt)
+
+	ctx := context.Background()
+	now := time.Now()
+	desc := metric.NewDescriptor("requests", metric.CounterInstrumentKind, number.Int64Kind)
+	res := resource.NewWithAttributes(semconv.HostNameKey.String("host"))
+	var records []exportmetric.Record
+	for _, labels := range [][]attribute.KeyValue{
+		// The label is used if the Resource has no service name.
+		{semconv.ServiceNameKey.String("label-service")},
+		{},
+	} {
+		agg := sumAgg.New(1)[0]
+		if err := agg.Update(ctx, number.NewInt64Number(1), &desc); err != nil {
+			t.Fatal(err)
+		}
+		set := attribute.NewSet(labels...)
+		records = append(records, exportmetric.NewRecord(&desc, &set, res, &agg, now, now))
+	}
+	if err := e.Export(ctx, &checkpointSet{records: records}); err != nil {
+		t.Fatalf("exporting metrics: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	got := make(map[interface{}]interface{})
+	for _, d := range mockt.Data {
+		for _, m := range d.Metrics {
+			got[d.Common.Attributes["entity.name"]] = m.Attributes["service.name"]
+		}
+	}
+	want := map[interface{}]interface{}{
+		"label-service":    "label-service",
+		"fallback-service": "fallback-service",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("service names: got %v, want %v", got, want)
+	}
+}
+
+// checkpointSet is an exportmetric.CheckpointSet of fixed records.
+type checkpointSet struct {
+	sync.RWMutex
+	records []exportmetric.Record
+}
+
+func (c *checkpointSet) ForEach(_ exportmetric.ExportKindSelector, f func(exportmetric.Record) error) error {
+	for _, r := range c.records {
+		if err := f(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSemconvTarget(t *testing.T) {
+	if _, err := NewExporterWithOptions("service", "apiKey", WithSemconvTarget("latest")); err == nil {
+		t.Error("expected invalid semantic conventions version error")
+	}
+
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithSemconvTarget("1.21.0"))
+
+	attrs := []attribute.KeyValue{attribute.String("net.peer.name", "example.com")}
+	span := &trace.SpanSnapshot{
+		SpanContext: testSpanContext(),
+		Name:        "span",
+		Attributes:  attrs,
+	}
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, []*trace.SpanSnapshot{span}); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if span.Attributes[0].Key != "net.peer.name" {
+		t.Errorf("exported span snapshot modified: %v", span.Attributes)
+	}
+
+	desc := metric.NewDescriptor("metric", metric.CounterInstrumentKind, number.Int64Kind)
+	agg := sumAgg.New(1)[0]
+	if err := agg.Update(ctx, number.NewInt64Number(1), &desc); err != nil {
+		t.Fatal(err)
+	}
+	labels := attribute.NewSet(attrs...)
+	now := time.Now()
+	cps := &checkpointSet{records: []exportmetric.Record{
+		exportmetric.NewRecord(&desc, &labels, nil, &agg, now, now),
+	}}
+	if err := e.Export(ctx, cps); err != nil {
+		t.Fatalf("exporting metrics: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	spans := mockt.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if got := spans[0].Attributes["server.address"]; got != "example.com" {
+		t.Errorf("span server.address: got %v, want example.com", got)
+	}
+	if _, ok := spans[0].Attributes["net.peer.name"]; ok {
+		t.Error("span net.peer.name not translated")
+	}
+	metrics := mockt.Metrics()
+	if len(metrics) != 1 {
+		t.Fatalf("expected 1 metric, got %d", len(metrics))
+	}
+	if got := metrics[0].Attributes["server.address"]; got != "example.com" {
+		t.Errorf("metric server.address: got %v, want example.com", got)
+	}
+	if _, ok := metrics[0].Attributes["net.peer.name"]; ok {
+		t.Error("metric net.peer.name not translated")
+	}
+}
+
+func TestClockSkewPolicy(t *testing.T) {
+	newSpans := func() []*trace.SpanSnapshot {
+		now := time.Now()
+		spans := newTestSpans("valid", "skewed", "invalid")
+		for _, s := range spans {
+			s.StartTime = now
+			s.EndTime = now.Add(time.Second)
+		}
+		spans[1].EndTime = now.Add(-time.Second)
+		spans[2].SpanContext = spans[2].SpanContext.WithTraceID(apitrace.TraceID{})
+		return spans
+	}
+
+	for _, test := range []struct {
+		policy ClockSkewPolicy
+		want   []string
+	}{
+		{policy: ClockSkewRepair, want: []string{"valid", "skewed"}},
+		{policy: ClockSkewDrop, want: []string{"valid"}},
+	} {
+		mockt := &MockTransport{}
+		e := newTestExporter(t, "service", mockt, WithClockSkewPolicy(test.policy))
+		ctx := context.Background()
+		if err := e.ExportSpans(ctx, newSpans()); err != nil {
+			t.Errorf("policy %d: exporting spans: %v", test.policy, err)
+		}
+		if err := e.Shutdown(ctx); err != nil {
+			t.Fatalf("shutting down exporter: %v", err)
+		}
+
+		var names []string
+		for _, s := range mockt.Spans() {
+			name := s.Attributes["name"].(string)
+			names = append(names, name)
+			_, skewed := s.Attributes["nr.clock_skew"]
+			if skewed != (name == "skewed") {
+				t.Errorf("policy %d: span %s: nr.clock_skew %t", test.policy, name, skewed)
+			}
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("policy %d: got spans %v, want %v", test.policy, names, test.want)
+		}
+	}
+}
+
+func TestClockSkewDerivedData(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithSpanMetrics(10), WithTransactions(0))
+
+	spans := newTestSpans("skewed")
+	now := time.Now()
+	spans[0].SpanKind = apitrace.SpanKindServer
+	spans[0].StartTime = now
+	spans[0].EndTime = now.Add(-time.Second)
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	// The transaction and span metrics have the zero duration of the
+	// repaired span.
+	if got := len(mockt.Events); got != 1 {
+		t.Fatalf("expecting 1 transaction event, got %d", got)
+	}
+	if got := mockt.Events[0]["duration"]; got != 0.0 {
+		t.Errorf("transaction duration: got %v, want 0", got)
+	}
+	summaries := 0
+	for _, m := range mockt.Metrics() {
+		switch m.Name {
+		case transactionDurationMetricName, spanDurationMetricName:
+			summaries++
+			if sum := m.Value.(map[string]interface{})["sum"]; sum != 0.0 {
+				t.Errorf("%s: got sum %v, want 0", m.Name, sum)
+			}
+		}
+	}
+	if summaries != 2 {
+		t.Errorf("expected 2 duration summaries, got %d", summaries)
+	}
+}
+
+func TestMetricNormalization(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithMetricNormalization(MetricNamespace("myapp"), MetricDurationsInSeconds()))
+
+	ctx := context.Background()
+	now := time.Now()
+	var records []exportmetric.Record
+	for _, test := range []struct {
+		desc   metric.Descriptor
+		labels []attribute.KeyValue
+	}{
+		{desc: metric.NewDescriptor("http/server duration", metric.CounterInstrumentKind, number.Int64Kind, metric.WithUnit("ms"))},
+		{desc: metric.NewDescriptor("received", metric.CounterInstrumentKind, number.Int64Kind, metric.WithUnit("By"))},
+		{desc: metric.NewDescriptor("myapp.ratio", metric.CounterInstrumentKind, number.Int64Kind, metric.WithUnit("1"))},
+		// A "unit" label is not the unit of the instrument.
+		{
+			desc:   metric.NewDescriptor("requests", metric.CounterInstrumentKind, number.Int64Kind),
+			labels: []attribute.KeyValue{attribute.String("unit", "ms")},
+		},
+	} {
+		desc := test.desc
+		agg := sumAgg.New(1)[0]
+		if err := agg.Update(ctx, number.NewInt64Number(1500), &desc); err != nil {
+			t.Fatal(err)
+		}
+		labels := attribute.NewSet(test.labels...)
+		records = append(records, exportmetric.NewRecord(&desc, &labels, nil, &agg, now, now))
+	}
+	if err := e.Export(ctx, &checkpointSet{records: records}); err != nil {
+		t.Fatalf("exporting metrics: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	want := map[string]struct {
+		value float64
+		unit  interface{}
+	}{
+		"myapp.http_server_duration": {value: 1.5, unit: "seconds"},
+		"myapp.received":             {value: 1500, unit: "bytes"},
+		"myapp.ratio":                {value: 1500},
+		"myapp.requests":             {value: 1500, unit: "ms"},
+	}
+	metrics := mockt.Metrics()
+	if len(metrics) != len(want) {
+		t.Fatalf("expected %d metrics, got %d", len(want), len(metrics))
+	}
+	for _, m := range metrics {
+		w, ok := want[m.Name]
+		if !ok {
+			t.Errorf("unexpected metric %q", m.Name)
+			continue
+		}
+		if m.Value != w.value {
+			t.Errorf("%s: value: got %v, want %v", m.Name, m.Value, w.value)
+		}
+		if got := m.Attributes["unit"]; got != w.unit {
+			t.Errorf("%s: unit: got %v, want %v", m.Name, got, w.unit)
+		}
+	}
+}
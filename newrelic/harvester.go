@@ -0,0 +1,330 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/transform"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// Harvester defaults.
+const (
+	// defaultHarvestPeriod is the harvest period of the telemetry SDK.
+	defaultHarvestPeriod = 5 * time.Second
+	// defaultMaxResources is the maximum number of Resources each
+	// destination keeps a harvester for.
+	defaultMaxResources = 1000
+	// defaultResourceIdleTimeout is how long the harvester of a Resource is
+	// kept without data being recorded with it.
+	defaultResourceIdleTimeout = 10 * time.Minute
+)
+
+// destination is a New Relic account or endpoint the Exporter sends data to.
+// Each destination has its own harvesters, so its data is batched, retried,
+// and sent independently of the other destinations.
+type destination struct {
+	name string
+	// options configure every harvester of the destination.
+	options []func(*telemetry.Config)
+	// health records the outcome of the requests sent to the destination.
+	health *destinationHealth
+
+	// harvester sends data that has no Resource attributes.
+	harvester *telemetry.Harvester
+	// harvesters send the data of each distinct Resource, keyed by the
+	// Resource equivalence key.
+	harvesters map[attribute.Distinct]*resourceHarvester
+	// evicted are the Resource harvesters evicted since the last harvest.
+	evicted []*telemetry.Harvester
+}
+
+// resourceHarvester is the harvester of a destination for a Resource.
+type resourceHarvester struct {
+	*telemetry.Harvester
+	lastUsed time.Time
+}
+
+// harvesters is the harvester of each destination data is recorded with. The
+// data is transformed once and the same values recorded with every
+// harvester.
+type harvesters []*telemetry.Harvester
+
+// RecordSpan records span with every harvester.
+func (hs harvesters) RecordSpan(span telemetry.Span) error {
+	var errs []string
+	for _, h := range hs {
+		if err := h.RecordSpan(span); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return joinErrors(errs)
+}
+
+// RecordMetric records m with every harvester.
+func (hs harvesters) RecordMetric(m telemetry.Metric) {
+	for _, h := range hs {
+		h.RecordMetric(m)
+	}
+}
+
+// RecordEvent records event with every harvester.
+func (hs harvesters) RecordEvent(event telemetry.Event) error {
+	var errs []string
+	for _, h := range hs {
+		if err := h.RecordEvent(event); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return joinErrors(errs)
+}
+
+// RecordLog records l with every harvester.
+func (hs harvesters) RecordLog(l telemetry.Log) error {
+	var errs []string
+	for _, h := range hs {
+		if err := h.RecordLog(l); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return joinErrors(errs)
+}
+
+// aggregate calls f with the metric aggregator of every harvester.
+func (hs harvesters) aggregate(f func(*telemetry.MetricAggregator)) {
+	for _, h := range hs {
+		f(h.MetricAggregator())
+	}
+}
+
+// joinErrors returns an error of the distinct messages errs, or nil if errs
+// is empty.
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(errs))
+	msgs := errs[:0]
+	for _, msg := range errs {
+		if !seen[msg] {
+			seen[msg] = true
+			msgs = append(msgs, msg)
+		}
+	}
+	return errors.New(strings.Join(msgs, ", "))
+}
+
+// harvesterFor returns the harvesters used to send data produced by res, one
+// for each default destination.
+func (e *Exporter) harvesterFor(res *resource.Resource) (harvesters, error) {
+	hs := make(harvesters, 0, len(e.destinations))
+	if res.Len() == 0 {
+		for _, d := range e.destinations {
+			hs = append(hs, d.harvester)
+		}
+		return hs, nil
+	}
+
+	now := time.Now()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, d := range e.destinations {
+		h, err := e.destinationHarvester(d, res, now)
+		if err != nil {
+			return nil, err
+		}
+		hs = append(hs, h)
+	}
+	return hs, nil
+}
+
+// destinationHarvester returns the harvester of d used to send data produced
+// by res at now. e.lock must be held if res has attributes.
+//
+// Each distinct Resource is sent with its own harvester so the Resource
+// attributes can be sent once per payload in the common block instead of
+// being repeated in every span and metric. Data without Resource attributes
+// is sent with the default harvester of the destination.
+//
+// At most e.maxResources harvesters are kept for each destination, the least
+// recently used is evicted to make room for a new one. Evicted harvesters
+// are sent the data they hold with the next harvest.
+func (e *Exporter) destinationHarvester(d *destination, res *resource.Resource, now time.Time) (*telemetry.Harvester, error) {
+	if res.Len() == 0 {
+		return d.harvester, nil
+	}
+	key := res.Equivalent()
+	if rh, ok := d.harvesters[key]; ok {
+		rh.lastUsed = now
+		return rh.Harvester, nil
+	}
+	h, err := e.newHarvester(d, res)
+	if err != nil {
+		return nil, err
+	}
+	if d.harvesters == nil {
+		d.harvesters = make(map[attribute.Distinct]*resourceHarvester)
+	}
+	if len(d.harvesters) >= e.maxResources {
+		var lruKey attribute.Distinct
+		var lru *resourceHarvester
+		for k, rh := range d.harvesters {
+			if lru == nil || rh.lastUsed.Before(lru.lastUsed) {
+				lruKey, lru = k, rh
+			}
+		}
+		delete(d.harvesters, lruKey)
+		d.evicted = append(d.evicted, lru.Harvester)
+	}
+	d.harvesters[key] = &resourceHarvester{Harvester: h, lastUsed: now}
+	return h, nil
+}
+
+// newHarvester creates a harvester of d that sends the attributes of res,
+// along with the resolved service identity, in the common block of each
+// payload. Spans and metrics share this identity so both are attributed to
+// the same New Relic entity.
+//
+// The harvester does not harvest by itself, the Exporter harvests it every
+// period so evicted harvesters are not left running.
+func (e *Exporter) newHarvester(d *destination, res *resource.Resource) (*telemetry.Harvester, error) {
+	attrs := e.commonAttributes(res)
+	options := d.options[:len(d.options):len(d.options)]
+	if attrs != nil {
+		options = append(options, telemetry.ConfigCommonAttributes(attrs))
+	}
+	options = append(options, telemetry.ConfigHarvestPeriod(0))
+	return telemetry.NewHarvester(options...)
+}
+
+// commonAttributes returns the attributes of res along with the resolved
+// service identity.
+func (e *Exporter) commonAttributes(res *resource.Resource) map[string]interface{} {
+	attrs := transform.Resource(res)
+	for k, v := range transform.ResolveService(e.serviceName, res).Attributes() {
+		if attrs == nil {
+			attrs = make(map[string]interface{})
+		}
+		attrs[k] = v
+	}
+	return attrs
+}
+
+// serviceResource returns res with the service.name attribute of attrs if
+// res has no service name. Data is then grouped, and its common block
+// identifies the service, with the name its span or metric is sent with:
+// the Resource service name, followed by the item attribute.
+func serviceResource(res *resource.Resource, attrs []attribute.KeyValue) *resource.Resource {
+	if transform.ResolveService("", res).Name != "" {
+		return res
+	}
+	for _, kv := range attrs {
+		if kv.Key == semconv.ServiceNameKey && kv.Value.AsString() != "" {
+			return resource.Merge(res, resource.NewWithAttributes(kv))
+		}
+	}
+	return res
+}
+
+// harvestable evicts the Resource harvesters of d idle at now and returns
+// the harvesters to harvest: the default and Resource harvesters of d and
+// those evicted since the last harvest. e.lock must be held.
+func (e *Exporter) harvestable(d *destination, now time.Time) []*telemetry.Harvester {
+	hs := make([]*telemetry.Harvester, 0, len(d.harvesters)+len(d.evicted)+1)
+	hs = append(hs, d.harvester)
+	for k, rh := range d.harvesters {
+		if now.Sub(rh.lastUsed) > e.resourceIdleTimeout {
+			delete(d.harvesters, k)
+		}
+		hs = append(hs, rh.Harvester)
+	}
+	hs = append(hs, d.evicted...)
+	d.evicted = nil
+	return hs
+}
+
+// harvestPeriod returns the harvest period configured by options.
+func harvestPeriod(options []func(*telemetry.Config)) time.Duration {
+	cfg := telemetry.Config{
+		Client:        &http.Client{},
+		HarvestPeriod: defaultHarvestPeriod,
+	}
+	for _, o := range options {
+		o(&cfg)
+	}
+	return cfg.HarvestPeriod
+}
+
+// run harvests e every period until it is shut down. Nothing is harvested
+// until Shutdown if the period is zero.
+func (e *Exporter) run(period time.Duration) {
+	defer close(e.done)
+	if period <= 0 {
+		return
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			e.harvestNow(context.Background())
+		}
+	}
+}
+
+// stopHarvests stops the periodic harvests of e.
+func (e *Exporter) stopHarvests() {
+	e.stopOnce.Do(func() { close(e.stop) })
+	<-e.done
+}
+
+// harvestNow sends all data held by the harvesters of e to New Relic.
+func (e *Exporter) harvestNow(ctx context.Context) {
+	e.harvestAt(ctx, time.Now())
+}
+
+// harvestAt sends all data held by the harvesters of e to New Relic,
+// evicting the routed destinations and Resource harvesters that are idle at
+// now. The destinations are harvested concurrently so a slow destination
+// does not delay the others.
+func (e *Exporter) harvestAt(ctx context.Context, now time.Time) {
+	// No data is recorded while the harvesters are collected, so none is
+	// recorded with an evicted harvester after it is harvested.
+	e.recording.Lock()
+	e.spanMetrics.reset()
+	destinations := e.destinations
+	if e.router != nil {
+		destinations = append(destinations[:len(destinations):len(destinations)], e.router.harvestable(now)...)
+	}
+	e.lock.Lock()
+	all := make([][]*telemetry.Harvester, 0, len(destinations))
+	for _, d := range destinations {
+		all = append(all, e.harvestable(d, now))
+	}
+	e.lock.Unlock()
+	e.recording.Unlock()
+
+	var wg sync.WaitGroup
+	for _, hs := range all {
+		wg.Add(1)
+		go func(hs []*telemetry.Harvester) {
+			defer wg.Done()
+			for _, h := range hs {
+				h.HarvestNow(ctx)
+			}
+		}(hs)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,244 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/com_newrelic_trace_v1"
+)
+
+// Infinite Tracing defaults.
+const (
+	defaultTraceObserverQueueSize = 10000
+
+	// apiKeyMetadataKey is the gRPC metadata the API key is sent with.
+	apiKeyMetadataKey = "api-key"
+
+	// keepaliveTime is how often an idle connection to the trace observer
+	// is pinged. It is below the idle timeout of common load balancers.
+	keepaliveTime    = 5 * time.Minute
+	keepaliveTimeout = 20 * time.Second
+)
+
+// traceObserverBackoff is the time waited before reconnecting to a trace
+// observer after a stream fails. It is a variable so tests can shorten it.
+var traceObserverBackoff = 15 * time.Second
+
+// errTraceObserverQueueFull is reported when a span is dropped because the
+// queue of spans waiting to be streamed is full.
+var errTraceObserverQueueFull = errors.New("infinite tracing: queue full, span dropped")
+
+// traceObserver streams spans to an Infinite Tracing trace observer over a
+// long-lived gRPC stream.
+//
+// Spans are queued and sent by a single goroutine, so a slow stream only
+// fills the bounded queue instead of blocking the application. The stream is
+// reopened, after a backoff, when it fails. Streaming stops for good if the
+// trace observer does not implement the RecordSpan method.
+type traceObserver struct {
+	conn     *grpc.ClientConn
+	client   v1.IngestServiceClient
+	metadata metadata.MD
+
+	messages chan *v1.Span
+
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+	done         chan struct{}
+}
+
+// newTraceObserver connects to the trace observer at host, "host:port",
+// authenticating with apiKey. The connection uses TLS unless overridden by
+// dialOptions.
+func newTraceObserver(host, apiKey string, queueSize int, dialOptions ...grpc.DialOption) (*traceObserver, error) {
+	if queueSize <= 0 {
+		queueSize = defaultTraceObserverQueueSize
+	}
+	options := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    keepaliveTime,
+			Timeout: keepaliveTimeout,
+		}),
+	}, dialOptions...)
+	conn, err := grpc.Dial(host, options...)
+	if err != nil {
+		return nil, fmt.Errorf("infinite tracing: dial %s: %w", host, err)
+	}
+
+	o := &traceObserver{
+		conn:     conn,
+		client:   v1.NewIngestServiceClient(conn),
+		metadata: metadata.Pairs(apiKeyMetadataKey, apiKey),
+		messages: make(chan *v1.Span, queueSize),
+		shutdown: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go o.run()
+	return o, nil
+}
+
+// send queues span to be streamed. The span is dropped if the queue is full
+// or the trace observer has been shut down.
+func (o *traceObserver) send(span *v1.Span) error {
+	select {
+	case <-o.shutdown:
+		return errors.New("infinite tracing: trace observer shut down")
+	default:
+	}
+	select {
+	case o.messages <- span:
+		return nil
+	default:
+		return errTraceObserverQueueFull
+	}
+}
+
+// run streams queued spans, reopening the stream when it fails, until the
+// trace observer is shut down.
+func (o *traceObserver) run() {
+	defer close(o.done)
+
+	// pending is a span that could not be sent on a failed stream. It is
+	// sent first on the next stream.
+	var pending *v1.Span
+	for {
+		var err error
+		pending, err = o.stream(pending)
+		if err == nil {
+			return
+		}
+		otel.Handle(fmt.Errorf("infinite tracing: %w", err))
+		switch status.Code(err) {
+		case codes.Unimplemented:
+			// The trace observer will never accept spans.
+			o.stopSending()
+			return
+		case codes.OK:
+			// The trace observer closed the stream and asks for it to be
+			// reopened right away.
+			continue
+		}
+		select {
+		case <-o.shutdown:
+			return
+		case <-time.After(traceObserverBackoff):
+		}
+	}
+}
+
+// stream opens a stream and sends pending followed by the queued spans. It
+// returns nil once the queue has been flushed after shutdown, or the status
+// error that ended the stream along with any span that was not sent.
+func (o *traceObserver) stream(pending *v1.Span) (*v1.Span, error) {
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(context.Background(), o.metadata))
+	defer cancel()
+
+	stream, err := o.client.RecordSpan(ctx)
+	if err != nil {
+		return pending, err
+	}
+
+	// The trace observer sends the number of spans it has seen. Reading
+	// these is required for the stream to make progress, and any error
+	// ends the stream.
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := stream.Recv(); err != nil {
+				recvErr <- err
+				return
+			}
+		}
+	}()
+	// closed returns the status the stream was closed with. A send fails
+	// with io.EOF when the stream has been closed, the status is then read
+	// from the receiving side.
+	closed := func(err error) error {
+		if err == io.EOF {
+			err = <-recvErr
+		}
+		if err == io.EOF {
+			return status.Error(codes.OK, "stream closed by trace observer")
+		}
+		return err
+	}
+
+	if pending != nil {
+		if err := stream.Send(pending); err != nil {
+			return pending, closed(err)
+		}
+	}
+	for {
+		select {
+		case span := <-o.messages:
+			if err := stream.Send(span); err != nil {
+				return span, closed(err)
+			}
+		case err := <-recvErr:
+			if err == io.EOF {
+				err = status.Error(codes.OK, "stream closed by trace observer")
+			}
+			return nil, err
+		case <-o.shutdown:
+			o.flush(stream, recvErr)
+			return nil, nil
+		}
+	}
+}
+
+// flush sends the queued spans, closes the stream, and waits for the trace
+// observer to close its side.
+func (o *traceObserver) flush(stream v1.IngestService_RecordSpanClient, recvErr <-chan error) {
+	// Only run receives messages, so this does not block.
+	for len(o.messages) > 0 {
+		if err := stream.Send(<-o.messages); err != nil {
+			return
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return
+	}
+	<-recvErr
+}
+
+// stopSending stops accepting spans and drops those queued.
+func (o *traceObserver) stopSending() {
+	o.shutdownOnce.Do(func() { close(o.shutdown) })
+	for {
+		select {
+		case <-o.messages:
+		default:
+			return
+		}
+	}
+}
+
+// close flushes the queued spans and closes the connection to the trace
+// observer, waiting until ctx is done.
+func (o *traceObserver) close(ctx context.Context) error {
+	o.shutdownOnce.Do(func() { close(o.shutdown) })
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+		o.conn.Close()
+		return ctx.Err()
+	}
+	return o.conn.Close()
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	apitrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	v1 "github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/com_newrelic_trace_v1"
+)
+
+// fakeTraceObserver is an in-process Infinite Tracing trace observer.
+type fakeTraceObserver struct {
+	v1.UnimplementedIngestServiceServer
+
+	lock  sync.Mutex
+	spans []*v1.Span
+	keys  []string
+	// failures are the errors the next streams fail with, one per stream.
+	failures []error
+
+	server   *grpc.Server
+	listener *bufconn.Listener
+}
+
+func newFakeTraceObserver(t *testing.T, failures ...error) *fakeTraceObserver {
+	o := &fakeTraceObserver{
+		failures: failures,
+		server:   grpc.NewServer(),
+		listener: bufconn.Listen(1 << 20),
+	}
+	v1.RegisterIngestServiceServer(o.server, o)
+	go o.server.Serve(o.listener)
+	t.Cleanup(o.server.Stop)
+	return o
+}
+
+// dialOptions connect to the fake trace observer.
+func (o *fakeTraceObserver) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return o.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+func (o *fakeTraceObserver) RecordSpan(stream v1.IngestService_RecordSpanServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	o.lock.Lock()
+	o.keys = append(o.keys, md.Get(apiKeyMetadataKey)...)
+	var failure error
+	if len(o.failures) > 0 {
+		failure, o.failures = o.failures[0], o.failures[1:]
+	}
+	o.lock.Unlock()
+
+	var seen uint64
+	for {
+		span, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if failure != nil {
+			// Fail the stream after receiving its first span.
+			return failure
+		}
+		o.lock.Lock()
+		o.spans = append(o.spans, span)
+		o.lock.Unlock()
+		seen++
+		if err := stream.Send(&v1.RecordStatus{MessagesSeen: seen}); err != nil {
+			return err
+		}
+	}
+}
+
+// names returns the names of the received spans.
+func (o *fakeTraceObserver) names() []string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	var names []string
+	for _, s := range o.spans {
+		names = append(names, s.Intrinsics["name"].GetStringValue())
+	}
+	return names
+}
+
+// streams returns the number of streams opened.
+func (o *fakeTraceObserver) streams() int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return len(o.keys)
+}
+
+func newTestSpans(names ...string) []*trace.SpanSnapshot {
+	traceID, _ := apitrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := apitrace.SpanIDFromHex("00f067aa0ba902b7")
+	var spans []*trace.SpanSnapshot
+	for _, name := range names {
+		spans = append(spans, &trace.SpanSnapshot{
+			SpanContext: apitrace.NewSpanContext(apitrace.SpanContextConfig{
+				TraceID: traceID,
+				SpanID:  spanID,
+			}),
+			Name:     name,
+			Resource: resource.NewWithAttributes(semconv.HostNameKey.String("host")),
+		})
+	}
+	return spans
+}
+
+func TestInfiniteTracing(t *testing.T) {
+	o := newFakeTraceObserver(t)
+	e := newTestExporter(t, "service", &MockTransport{}, WithInfiniteTracing("trace-observer:443", 0, o.dialOptions()...))
+
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, newTestSpans("a", "b")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	if got := o.names(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("streamed spans: got %v, want [a b]", got)
+	}
+	span := o.spans[0]
+	if got := span.TraceId; got != testTraceID.String() {
+		t.Errorf("trace ID: got %s", got)
+	}
+	if got := span.AgentAttributes["host.name"].GetStringValue(); got != "host" {
+		t.Errorf("resource attribute: got %q, want host", got)
+	}
+	if got := span.Intrinsics["service.name"].GetStringValue(); got != "service" {
+		t.Errorf("service name: got %q, want service", got)
+	}
+	if len(o.keys) == 0 || o.keys[0] != "apiKey" {
+		t.Errorf("API key metadata: got %v", o.keys)
+	}
+}
+
+func TestInfiniteTracingReconnect(t *testing.T) {
+	defer func(d time.Duration) { traceObserverBackoff = d }(traceObserverBackoff)
+	traceObserverBackoff = time.Millisecond
+
+	o := newFakeTraceObserver(t, status.Error(codes.Unavailable, "unavailable"))
+	e := newTestExporter(t, "service", &MockTransport{}, WithInfiniteTracing("trace-observer:443", 0, o.dialOptions()...))
+
+	// The first stream fails after its first span and is reopened.
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, newTestSpans("a")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for o.streams() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := o.streams(); got != 2 {
+		t.Fatalf("expected 2 streams, got %d", got)
+	}
+
+	if err := e.ExportSpans(ctx, newTestSpans("b")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+	if got := o.names(); len(got) != 1 || got[0] != "b" {
+		t.Errorf("streamed spans: got %v, want [b]", got)
+	}
+}
+
+func TestInfiniteTracingUnimplemented(t *testing.T) {
+	o := newFakeTraceObserver(t, status.Error(codes.Unimplemented, "unimplemented"))
+	e := newTestExporter(t, "service", &MockTransport{}, WithInfiniteTracing("trace-observer:443", 0, o.dialOptions()...))
+
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, newTestSpans("a")); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	select {
+	case <-e.traceObserver.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("trace observer did not stop")
+	}
+	if err := e.ExportSpans(ctx, newTestSpans("b")); err == nil {
+		t.Error("expected error exporting spans after the trace observer stopped")
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+}
+
+func TestInfiniteTracingQueueFull(t *testing.T) {
+	o := &traceObserver{
+		messages: make(chan *v1.Span, 1),
+		shutdown: make(chan struct{}),
+	}
+	if err := o.send(&v1.Span{}); err != nil {
+		t.Fatalf("sending first span: %v", err)
+	}
+	if err := o.send(&v1.Span{}); err != errTraceObserverQueueFull {
+		t.Errorf("sending to a full queue: got %v, want %v", err, errTraceObserverQueueFull)
+	}
+}
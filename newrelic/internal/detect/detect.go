@@ -0,0 +1,178 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package detect provides OpenTelemetry Resource detectors for the container
+// and orchestration environment a service runs in, which the OpenTelemetry
+// SDK does not detect.
+package detect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// System is the view of the operating system the detectors inspect. Tests
+// replace it with fixtures.
+type System struct {
+	// Root is the directory the /proc and /var/run file systems are read
+	// relative to.
+	Root string
+	// LookupEnv returns the value of an environment variable.
+	LookupEnv func(string) (string, bool)
+}
+
+// OSSystem returns the System of the running process.
+func OSSystem() System {
+	return System{
+		Root:      "/",
+		LookupEnv: os.LookupEnv,
+	}
+}
+
+func (s System) path(elem ...string) string {
+	return filepath.Join(append([]string{s.Root}, elem...)...)
+}
+
+// detectorFunc is a resource.Detector implemented by a function.
+type detectorFunc func(context.Context) (*resource.Resource, error)
+
+func (f detectorFunc) Detect(ctx context.Context) (*resource.Resource, error) {
+	return f(ctx)
+}
+
+var (
+	// cgroupContainerID matches the container ID at the end of a cgroup v1
+	// path, e.g. /docker/<id>, /kubepods/<pod>/<id>, or
+	// /system.slice/cri-containerd-<id>.scope.
+	cgroupContainerID = regexp.MustCompile(`([0-9a-f]{64})(?:\.scope)?$`)
+	// mountContainerID matches the container ID in the container runtime
+	// paths found in the cgroup v2 mountinfo.
+	mountContainerID = regexp.MustCompile(`/containers/([0-9a-f]{64})/`)
+)
+
+// Container returns a detector for the ID of the container the process runs
+// in. It reads the cgroup v1 /proc/self/cgroup file and falls back to the
+// cgroup v2 /proc/self/mountinfo file. No attributes are detected outside of
+// a container.
+func Container(s System) resource.Detector {
+	return detectorFunc(func(context.Context) (*resource.Resource, error) {
+		id, err := containerID(s)
+		if err != nil || id == "" {
+			return resource.Empty(), err
+		}
+		return resource.NewWithAttributes(semconv.ContainerIDKey.String(id)), nil
+	})
+}
+
+func containerID(s System) (string, error) {
+	cgroup, err := ioutil.ReadFile(s.path("proc", "self", "cgroup"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("detect container: %w", err)
+	}
+	for _, line := range strings.Split(string(cgroup), "\n") {
+		// Each line is hierarchy-ID:controller-list:cgroup-path.
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if m := cgroupContainerID.FindStringSubmatch(parts[2]); m != nil {
+			return m[1], nil
+		}
+	}
+
+	mountinfo, err := ioutil.ReadFile(s.path("proc", "self", "mountinfo"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("detect container: %w", err)
+	}
+	if m := mountContainerID.FindStringSubmatch(string(mountinfo)); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
+
+// Environment variables read by the Kubernetes detector. These are expected
+// to be set with the Kubernetes downward API.
+var (
+	podNameEnv   = []string{"K8S_POD_NAME", "POD_NAME"}
+	podUIDEnv    = []string{"K8S_POD_UID", "POD_UID"}
+	namespaceEnv = []string{"K8S_NAMESPACE_NAME", "K8S_NAMESPACE", "POD_NAMESPACE"}
+	nodeNameEnv  = []string{"K8S_NODE_NAME", "NODE_NAME"}
+)
+
+// Kubernetes returns a detector for the Kubernetes pod, namespace, and node
+// the process runs in. The values are read from environment variables set
+// with the Kubernetes downward API, the namespace falls back to the mounted
+// service account namespace. No attributes are detected outside of
+// Kubernetes.
+func Kubernetes(s System) resource.Detector {
+	return detectorFunc(func(context.Context) (*resource.Resource, error) {
+		if _, ok := s.LookupEnv("KUBERNETES_SERVICE_HOST"); !ok {
+			return resource.Empty(), nil
+		}
+
+		var attrs []attribute.KeyValue
+		add := func(key attribute.Key, names []string) bool {
+			for _, name := range names {
+				if v, ok := s.LookupEnv(name); ok && v != "" {
+					attrs = append(attrs, key.String(v))
+					return true
+				}
+			}
+			return false
+		}
+		add(semconv.K8SPodNameKey, podNameEnv)
+		add(semconv.K8SPodUIDKey, podUIDEnv)
+		add(semconv.K8SNodeNameKey, nodeNameEnv)
+		if !add(semconv.K8SNamespaceNameKey, namespaceEnv) {
+			ns, err := ioutil.ReadFile(s.path("var", "run", "secrets", "kubernetes.io", "serviceaccount", "namespace"))
+			if err == nil && len(ns) > 0 {
+				attrs = append(attrs, semconv.K8SNamespaceNameKey.String(strings.TrimSpace(string(ns))))
+			}
+		}
+		return resource.NewWithAttributes(attrs...), nil
+	})
+}
+
+// Environment returns a detector for the Resource attributes in the
+// OTEL_RESOURCE_ATTRIBUTES environment variable. The attributes are read by
+// the OpenTelemetry SDK resource.FromEnv detector and their percent-encoded
+// keys and values decoded. Invalid attributes are skipped and reported with
+// a resource.ErrPartialResource error.
+func Environment() resource.Detector {
+	return detectorFunc(func(ctx context.Context) (*resource.Resource, error) {
+		res, err := resource.FromEnv{}.Detect(ctx)
+		if res == nil {
+			return res, err
+		}
+
+		var (
+			attrs   []attribute.KeyValue
+			invalid []string
+		)
+		for iter := res.Iter(); iter.Next(); {
+			kv := iter.Label()
+			k, kErr := url.PathUnescape(string(kv.Key))
+			v, vErr := url.PathUnescape(kv.Value.AsString())
+			if kErr != nil || vErr != nil || k == "" {
+				invalid = append(invalid, string(kv.Key)+"="+kv.Value.AsString())
+				continue
+			}
+			attrs = append(attrs, attribute.String(k, v))
+		}
+		if len(invalid) > 0 && err == nil {
+			err = fmt.Errorf("%w: invalid attributes: %q", resource.ErrPartialResource, invalid)
+		}
+		return resource.NewWithAttributes(attrs...), err
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package detect
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+const sampleContainerID = "a4d4b6e6d14ba54a6ae0e5a0d7bf8c6b48cd4b1a6e38c9b9a2a8c9f1d5e6f7a8"
+
+// fixture returns a System rooted at a temporary directory containing files
+// and with the environment env.
+func fixture(t *testing.T, files, env map[string]string) System {
+	root, err := ioutil.TempDir("", "detect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return System{
+		Root: root,
+		LookupEnv: func(key string) (string, bool) {
+			v, ok := env[key]
+			return v, ok
+		},
+	}
+}
+
+func detect(t *testing.T, d resource.Detector) map[attribute.Key]attribute.Value {
+	res, err := d.Detect(context.Background())
+	if err != nil {
+		t.Fatalf("detect: %v", err)
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for iter := res.Iter(); iter.Next(); {
+		kv := iter.Label()
+		got[kv.Key] = kv.Value
+	}
+	return got
+}
+
+func TestContainer(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "not in a container",
+			files: map[string]string{
+				"proc/self/cgroup": "12:pids:/user.slice/user-1000.slice\n0::/user.slice\n",
+			},
+		},
+		{
+			name: "docker cgroup v1",
+			files: map[string]string{
+				"proc/self/cgroup": "12:pids:/docker/" + sampleContainerID + "\n11:memory:/docker/" + sampleContainerID + "\n",
+			},
+			want: sampleContainerID,
+		},
+		{
+			name: "kubernetes cgroup v1",
+			files: map[string]string{
+				"proc/self/cgroup": "3:cpu,cpuacct:/kubepods/besteffort/pod2ac6b8d5-7c0c-4a3b-9d43-9d4b5f0a1f3e/" + sampleContainerID + "\n",
+			},
+			want: sampleContainerID,
+		},
+		{
+			name: "containerd systemd cgroup v1",
+			files: map[string]string{
+				"proc/self/cgroup": "1:name=systemd:/system.slice/cri-containerd-" + sampleContainerID + ".scope\n",
+			},
+			want: sampleContainerID,
+		},
+		{
+			name: "cgroup v2 mountinfo",
+			files: map[string]string{
+				"proc/self/cgroup":    "0::/\n",
+				"proc/self/mountinfo": "736 719 0:112 / / rw,relatime master:176 - overlay overlay rw\n750 736 254:1 /var/lib/docker/containers/" + sampleContainerID + "/resolv.conf /etc/resolv.conf rw,relatime - ext4 /dev/vda1 rw\n",
+			},
+			want: sampleContainerID,
+		},
+		{
+			name: "no proc file system",
+		},
+	} {
+		got := detect(t, Container(fixture(t, test.files, nil)))
+		if test.want == "" {
+			if len(got) != 0 {
+				t.Errorf("%s: got %v, want no attributes", test.name, got)
+			}
+			continue
+		}
+		if id := got[semconv.ContainerIDKey].AsString(); id != test.want {
+			t.Errorf("%s: got container ID %q, want %q", test.name, id, test.want)
+		}
+	}
+}
+
+func TestKubernetes(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		files map[string]string
+		env   map[string]string
+		want  map[attribute.Key]attribute.Value
+	}{
+		{
+			name: "not in kubernetes",
+			env:  map[string]string{"K8S_POD_NAME": "pod"},
+			want: map[attribute.Key]attribute.Value{},
+		},
+		{
+			name: "downward API",
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+				"K8S_POD_NAME":            "pod",
+				"K8S_POD_UID":             "uid",
+				"K8S_NAMESPACE_NAME":      "namespace",
+				"K8S_NODE_NAME":           "node",
+			},
+			want: map[attribute.Key]attribute.Value{
+				semconv.K8SPodNameKey:       attribute.StringValue("pod"),
+				semconv.K8SPodUIDKey:        attribute.StringValue("uid"),
+				semconv.K8SNamespaceNameKey: attribute.StringValue("namespace"),
+				semconv.K8SNodeNameKey:      attribute.StringValue("node"),
+			},
+		},
+		{
+			name: "service account namespace",
+			files: map[string]string{
+				"var/run/secrets/kubernetes.io/serviceaccount/namespace": "namespace\n",
+			},
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+				"POD_NAME":                "pod",
+			},
+			want: map[attribute.Key]attribute.Value{
+				semconv.K8SPodNameKey:       attribute.StringValue("pod"),
+				semconv.K8SNamespaceNameKey: attribute.StringValue("namespace"),
+			},
+		},
+	} {
+		got := detect(t, Kubernetes(fixture(t, test.files, test.env)))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// setResourceAttributes sets OTEL_RESOURCE_ATTRIBUTES for the duration of
+// the test.
+func setResourceAttributes(t *testing.T, value string) {
+	os.Setenv("OTEL_RESOURCE_ATTRIBUTES", value)
+	t.Cleanup(func() { os.Unsetenv("OTEL_RESOURCE_ATTRIBUTES") })
+}
+
+func TestEnvironment(t *testing.T) {
+	setResourceAttributes(t, "service.name=env service, deployment.environment=production,team=a%2Cb%3Dc,%C3%A9t%C3%A9=summer%20")
+	got := detect(t, Environment())
+	want := map[attribute.Key]attribute.Value{
+		semconv.ServiceNameKey:   attribute.StringValue("env service"),
+		"deployment.environment": attribute.StringValue("production"),
+		"team":                   attribute.StringValue("a,b=c"),
+		"été":                    attribute.StringValue("summer "),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEnvironmentInvalid(t *testing.T) {
+	for _, value := range []string{"a=1,invalid,=2", "a=1,b=%zz", "a=1,%=2"} {
+		setResourceAttributes(t, value)
+		res, err := Environment().Detect(context.Background())
+		if !errors.Is(err, resource.ErrPartialResource) {
+			t.Errorf("%s: expected partial resource error, got %v", value, err)
+		}
+		if res.Len() != 1 {
+			t.Errorf("%s: expected only valid attributes, got %v", value, res)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"fmt"
+
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+// New Relic span attributes used by the distributed tracing UI to render
+// external and datastore calls.
+const (
+	categoryAttrKey       = "category"
+	componentAttrKey      = "component"
+	httpMethodAttrKey     = "http.method"
+	httpStatusCodeAttrKey = "http.statusCode"
+	httpURLAttrKey        = "http.url"
+	dbInstanceAttrKey     = "db.instance"
+	dbCollectionAttrKey   = "db.collection"
+	peerHostnameAttrKey   = "peer.hostname"
+	peerAddressAttrKey    = "peer.address"
+	peerServiceAttrKey    = "peer.service"
+
+	categoryHTTP      = "http"
+	categoryDatastore = "datastore"
+	categoryGeneric   = "generic"
+)
+
+// OpenTelemetry semantic convention attributes the New Relic attributes are
+// derived from. Both the older and newer names of renamed attributes are
+// listed, in order of preference.
+var (
+	semconvHTTPMethod     = []string{"http.method", "http.request.method"}
+	semconvHTTPStatusCode = []string{"http.status_code", "http.response.status_code"}
+	semconvHTTPURL        = []string{"http.url", "url.full"}
+	semconvDBSystem       = []string{"db.system"}
+	semconvDBName         = []string{"db.name", "db.namespace"}
+	semconvDBCollection   = []string{"db.sql.table", "db.mongodb.collection", "db.cassandra.table", "db.collection.name"}
+	semconvRPCSystem      = []string{"rpc.system"}
+	semconvMessaging      = []string{"messaging.system"}
+	semconvPeerName       = []string{"net.peer.name", "server.address"}
+	semconvPeerPort       = []string{"net.peer.port", "server.port"}
+)
+
+// lookup returns the value of the first key of keys contained in attrs.
+func lookup(attrs map[string]interface{}, keys []string) (interface{}, bool) {
+	for _, k := range keys {
+		if v, ok := attrs[k]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// setDefault sets key to value in attrs unless it is already set, so
+// original attributes are always preserved.
+func setDefault(attrs map[string]interface{}, key string, value interface{}) {
+	if _, ok := attrs[key]; !ok {
+		attrs[key] = value
+	}
+}
+
+// enrich adds the New Relic span category attributes derived from the
+// OpenTelemetry HTTP, database, RPC, and messaging semantic convention
+// attributes of a span of kind. Existing attributes are never overwritten.
+func enrich(kind apitrace.SpanKind, attrs map[string]interface{}) {
+	if v, ok := lookup(attrs, semconvHTTPMethod); ok {
+		setDefault(attrs, httpMethodAttrKey, v)
+	}
+	if v, ok := lookup(attrs, semconvHTTPStatusCode); ok {
+		setDefault(attrs, httpStatusCodeAttrKey, v)
+	}
+	if v, ok := lookup(attrs, semconvHTTPURL); ok {
+		setDefault(attrs, httpURLAttrKey, v)
+	}
+
+	// Categories only describe calls out of the service.
+	if kind != apitrace.SpanKindClient && kind != apitrace.SpanKindProducer && kind != apitrace.SpanKindUnspecified {
+		return
+	}
+
+	category := ""
+	if system, ok := lookup(attrs, semconvDBSystem); ok {
+		category = categoryDatastore
+		setDefault(attrs, componentAttrKey, system)
+		if v, ok := lookup(attrs, semconvDBName); ok {
+			setDefault(attrs, dbInstanceAttrKey, v)
+		}
+		if v, ok := lookup(attrs, semconvDBCollection); ok {
+			setDefault(attrs, dbCollectionAttrKey, v)
+		}
+	} else if system, ok := lookup(attrs, semconvRPCSystem); ok {
+		category = categoryHTTP
+		setDefault(attrs, componentAttrKey, system)
+	} else if _, ok := attrs[httpMethodAttrKey]; ok {
+		category = categoryHTTP
+		setDefault(attrs, componentAttrKey, categoryHTTP)
+	} else if system, ok := lookup(attrs, semconvMessaging); ok {
+		category = categoryGeneric
+		setDefault(attrs, componentAttrKey, system)
+	}
+	if category == "" {
+		return
+	}
+	setDefault(attrs, categoryAttrKey, category)
+
+	if host, ok := lookup(attrs, semconvPeerName); ok {
+		setDefault(attrs, peerHostnameAttrKey, host)
+		setDefault(attrs, peerServiceAttrKey, host)
+		if port, ok := lookup(attrs, semconvPeerPort); ok {
+			setDefault(attrs, peerAddressAttrKey, fmt.Sprintf("%v:%v", host, port))
+		}
+	}
+}
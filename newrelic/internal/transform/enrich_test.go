@@ -0,0 +1,170 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestEnrich(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		kind  apitrace.SpanKind
+		attrs map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{
+			name:  "no semantic conventions",
+			kind:  apitrace.SpanKindClient,
+			attrs: map[string]interface{}{"foo": "bar"},
+			want:  map[string]interface{}{"foo": "bar"},
+		},
+		{
+			name: "http client",
+			kind: apitrace.SpanKindClient,
+			attrs: map[string]interface{}{
+				"http.method":      "GET",
+				"http.status_code": int64(200),
+				"http.url":         "https://example.com/a",
+				"net.peer.name":    "example.com",
+				"net.peer.port":    int64(443),
+			},
+			want: map[string]interface{}{
+				"http.method":      "GET",
+				"http.status_code": int64(200),
+				"http.url":         "https://example.com/a",
+				"net.peer.name":    "example.com",
+				"net.peer.port":    int64(443),
+				"http.statusCode":  int64(200),
+				"category":         "http",
+				"component":        "http",
+				"peer.hostname":    "example.com",
+				"peer.address":     "example.com:443",
+				"peer.service":     "example.com",
+			},
+		},
+		{
+			name: "http client with new semantic conventions",
+			kind: apitrace.SpanKindClient,
+			attrs: map[string]interface{}{
+				"http.request.method":       "POST",
+				"http.response.status_code": int64(500),
+				"url.full":                  "https://example.com/b",
+				"server.address":            "example.com",
+			},
+			want: map[string]interface{}{
+				"http.request.method":       "POST",
+				"http.response.status_code": int64(500),
+				"url.full":                  "https://example.com/b",
+				"server.address":            "example.com",
+				"http.method":               "POST",
+				"http.statusCode":           int64(500),
+				"http.url":                  "https://example.com/b",
+				"category":                  "http",
+				"component":                 "http",
+				"peer.hostname":             "example.com",
+				"peer.service":              "example.com",
+			},
+		},
+		{
+			name: "http server",
+			kind: apitrace.SpanKindServer,
+			attrs: map[string]interface{}{
+				"http.method":      "GET",
+				"http.status_code": int64(404),
+				"net.peer.name":    "client",
+			},
+			want: map[string]interface{}{
+				"http.method":      "GET",
+				"http.status_code": int64(404),
+				"net.peer.name":    "client",
+				"http.statusCode":  int64(404),
+			},
+		},
+		{
+			name: "datastore",
+			kind: apitrace.SpanKindClient,
+			attrs: map[string]interface{}{
+				"db.system":     "postgresql",
+				"db.name":       "orders",
+				"db.sql.table":  "items",
+				"db.statement":  "SELECT * FROM items",
+				"net.peer.name": "db",
+				"net.peer.port": int64(5432),
+				"peer.service":  "orders-db",
+			},
+			want: map[string]interface{}{
+				"db.system":     "postgresql",
+				"db.name":       "orders",
+				"db.sql.table":  "items",
+				"db.statement":  "SELECT * FROM items",
+				"net.peer.name": "db",
+				"net.peer.port": int64(5432),
+				"peer.service":  "orders-db",
+				"category":      "datastore",
+				"component":     "postgresql",
+				"db.instance":   "orders",
+				"db.collection": "items",
+				"peer.hostname": "db",
+				"peer.address":  "db:5432",
+			},
+		},
+		{
+			name: "rpc",
+			kind: apitrace.SpanKindClient,
+			attrs: map[string]interface{}{
+				"rpc.system": "grpc",
+			},
+			want: map[string]interface{}{
+				"rpc.system": "grpc",
+				"category":   "http",
+				"component":  "grpc",
+			},
+		},
+		{
+			name: "messaging producer",
+			kind: apitrace.SpanKindProducer,
+			attrs: map[string]interface{}{
+				"messaging.system": "kafka",
+			},
+			want: map[string]interface{}{
+				"messaging.system": "kafka",
+				"category":         "generic",
+				"component":        "kafka",
+			},
+		},
+		{
+			name: "messaging consumer",
+			kind: apitrace.SpanKindConsumer,
+			attrs: map[string]interface{}{
+				"messaging.system": "kafka",
+			},
+			want: map[string]interface{}{
+				"messaging.system": "kafka",
+			},
+		},
+		{
+			name: "existing attributes preserved",
+			kind: apitrace.SpanKindClient,
+			attrs: map[string]interface{}{
+				"db.system": "redis",
+				"category":  "custom",
+				"component": "go-redis",
+			},
+			want: map[string]interface{}{
+				"db.system": "redis",
+				"category":  "custom",
+				"component": "go-redis",
+			},
+		},
+	} {
+		enrich(test.kind, test.attrs)
+		if !reflect.DeepEqual(test.attrs, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.name, test.attrs, test.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Event transforms a custom event into a New Relic Event. The Event API has
+// no common block, so the common attributes are copied into the event and
+// overridden by kvs. The event is linked to the span identified by sc if it
+// is valid.
+//
+// https://godoc.org/github.com/newrelic/newrelic-telemetry-sdk-go/telemetry#Event
+func Event(eventType string, timestamp time.Time, kvs []attribute.KeyValue, common map[string]interface{}, sc trace.SpanContext) telemetry.Event {
+	// Account for the instrumentation provider and collector name.
+	numAttrs := len(common) + len(kvs) + 2
+	if sc.IsValid() {
+		numAttrs += 2
+	}
+
+	attrs := make(map[string]interface{}, numAttrs)
+	for k, v := range common {
+		attrs[k] = v
+	}
+	for _, kv := range kvs {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	if sc.IsValid() {
+		attrs[traceIDAttrKey] = sc.TraceID().String()
+		attrs[spanIDAttrKey] = sc.SpanID().String()
+	}
+
+	// New Relic registered attributes to identify where this data came from.
+	attrs[instrumentationProviderAttrKey] = instrumentationProviderAttrValue
+	attrs[collectorNameAttrKey] = collectorNameAttrValue
+
+	return telemetry.Event{
+		EventType:  eventType,
+		Timestamp:  timestamp,
+		Attributes: attrs,
+	}
+}
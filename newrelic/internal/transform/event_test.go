@@ -0,0 +1,71 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTransformEvent(t *testing.T) {
+	now := time.Now()
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: sampleTraceID,
+		SpanID:  sampleSpanID,
+	})
+	testcases := []struct {
+		testname string
+		kvs      []attribute.KeyValue
+		common   map[string]interface{}
+		sc       trace.SpanContext
+		expect   telemetry.Event
+	}{
+		{
+			testname: "basic event",
+			expect: telemetry.Event{
+				EventType: "CheckoutCompleted",
+				Timestamp: now,
+				Attributes: map[string]interface{}{
+					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
+					collectorNameAttrKey:           collectorNameAttrValue,
+				},
+			},
+		},
+		{
+			testname: "event with common attributes",
+			kvs: []attribute.KeyValue{
+				attribute.Float64("total", 9.99),
+				attribute.String("host.name", "event host"),
+			},
+			common: map[string]interface{}{
+				serviceNameAttrKey: service,
+				"host.name":        "resource host",
+			},
+			sc: sc,
+			expect: telemetry.Event{
+				EventType: "CheckoutCompleted",
+				Timestamp: now,
+				Attributes: map[string]interface{}{
+					"total":                        9.99,
+					"host.name":                    "event host",
+					serviceNameAttrKey:             service,
+					traceIDAttrKey:                 sampleTraceIDString,
+					spanIDAttrKey:                  sampleSpanIDString,
+					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
+					collectorNameAttrKey:           collectorNameAttrValue,
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		if got := Event("CheckoutCompleted", now, tc.kvs, tc.common, tc.sc); !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("%s: %#v != %#v", tc.testname, got, tc.expect)
+		}
+	}
+}
@@ -9,6 +9,22 @@ const (
 
 	serviceNameAttrKey = "service.name"
 
+	traceIDAttrKey  = "trace.id"
+	spanIDAttrKey   = "span.id"
+	logLevelAttrKey = "level"
+
+	entityNameAttrKey   = "entity.name"
+	entityGUIDAttrKey   = "entity.guid"
+	entityTypeAttrKey   = "entity.type"
+	entityTypeAttrValue = "SERVICE"
+
+	droppedAttributesCountAttrKey = "otel.dropped_attributes_count"
+	droppedEventsCountAttrKey     = "otel.dropped_events_count"
+	droppedLinksCountAttrKey      = "otel.dropped_links_count"
+	childSpanCountAttrKey         = "otel.child_span_count"
+
+	clockSkewAttrKey = "nr.clock_skew"
+
 	instrumentationProviderAttrKey   = "instrumentation.provider"
 	instrumentationProviderAttrValue = "opentelemetry"
 
@@ -0,0 +1,46 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Log transforms a structured log message into a New Relic Log. The message
+// is linked to the span identified by sc if it is valid.
+//
+// https://godoc.org/github.com/newrelic/newrelic-telemetry-sdk-go/telemetry#Log
+func Log(message, level string, timestamp time.Time, kvs []attribute.KeyValue, sc trace.SpanContext) telemetry.Log {
+	// Account for the level, instrumentation provider and collector name.
+	numAttrs := len(kvs) + 3
+	if sc.IsValid() {
+		numAttrs += 2
+	}
+
+	attrs := make(map[string]interface{}, numAttrs)
+	for _, kv := range kvs {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	if level != "" {
+		attrs[logLevelAttrKey] = level
+	}
+	if sc.IsValid() {
+		attrs[traceIDAttrKey] = sc.TraceID().String()
+		attrs[spanIDAttrKey] = sc.SpanID().String()
+	}
+
+	// New Relic registered attributes to identify where this data came from.
+	attrs[instrumentationProviderAttrKey] = instrumentationProviderAttrValue
+	attrs[collectorNameAttrKey] = collectorNameAttrValue
+
+	return telemetry.Log{
+		Message:    message,
+		Timestamp:  timestamp,
+		Attributes: attrs,
+	}
+}
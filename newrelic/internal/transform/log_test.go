@@ -0,0 +1,79 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTransformLog(t *testing.T) {
+	now := time.Now()
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: sampleTraceID,
+		SpanID:  sampleSpanID,
+	})
+	testcases := []struct {
+		testname string
+		level    string
+		kvs      []attribute.KeyValue
+		sc       trace.SpanContext
+		expect   telemetry.Log
+	}{
+		{
+			testname: "basic log",
+			expect: telemetry.Log{
+				Message:   "message",
+				Timestamp: now,
+				Attributes: map[string]interface{}{
+					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
+					collectorNameAttrKey:           collectorNameAttrValue,
+				},
+			},
+		},
+		{
+			testname: "log with level and attributes",
+			level:    "INFO",
+			kvs: []attribute.KeyValue{
+				attribute.String("user", "gopher"),
+				attribute.Int("attempt", 2),
+			},
+			expect: telemetry.Log{
+				Message:   "message",
+				Timestamp: now,
+				Attributes: map[string]interface{}{
+					"user":                         "gopher",
+					"attempt":                      int64(2),
+					logLevelAttrKey:                "INFO",
+					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
+					collectorNameAttrKey:           collectorNameAttrValue,
+				},
+			},
+		},
+		{
+			testname: "log in span",
+			sc:       sc,
+			expect: telemetry.Log{
+				Message:   "message",
+				Timestamp: now,
+				Attributes: map[string]interface{}{
+					traceIDAttrKey:                 sampleTraceIDString,
+					spanIDAttrKey:                  sampleSpanIDString,
+					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
+					collectorNameAttrKey:           collectorNameAttrValue,
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		if got := Log("message", tc.level, now, tc.kvs, tc.sc); !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("%s: %#v != %#v", tc.testname, got, tc.expect)
+		}
+	}
+}
@@ -104,7 +104,7 @@ func minMaxSumCount(desc *metric.Descriptor, attrs map[string]interface{}, a agg
 
 func attributes(service string, res *resource.Resource, desc *metric.Descriptor, labels *attribute.Set) map[string]interface{} {
 	// By default include New Relic attributes and all labels
-	n := 2 + labels.Len() + res.Len()
+	n := 2 + labels.Len()
 	if desc != nil {
 		if desc.Unit() != "" {
 			n++
@@ -113,26 +113,25 @@ func attributes(service string, res *resource.Resource, desc *metric.Descriptor,
 			n++
 		}
 	}
-	if service != "" {
+	resService := ResolveService("", res).Name
+	if resService != "" || service != "" {
 		n++
 	}
 	attrs := make(map[string]interface{}, n)
 
-	if service != "" {
-		// This is intentionally overwritten by the resource and then the
-		// instrument itself if they contain the service name.
-		attrs[serviceNameAttrKey] = service
-	}
-
-	for iter := res.Iter(); iter.Next(); {
+	// Resource attributes are sent in the common block of the payload
+	// instead of with every metric.
+	for iter := labels.Iter(); iter.Next(); {
 		kv := iter.Label()
 		attrs[string(kv.Key)] = kv.Value.AsInterface()
 	}
 
-	// If duplicate labels with Resource these take precedence.
-	for iter := labels.Iter(); iter.Next(); {
-		kv := iter.Label()
-		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	// The Resource service name is authoritative, followed by the label and
+	// then the exporter service name. This matches the name used for spans.
+	if resService != "" {
+		attrs[serviceNameAttrKey] = resService
+	} else if _, ok := attrs[serviceNameAttrKey]; !ok && service != "" {
+		attrs[serviceNameAttrKey] = service
 	}
 
 	if desc != nil {
@@ -47,12 +47,20 @@ func TestServiceNameAttributes(t *testing.T) {
 		t.Errorf("service.name attribute wrong: got %q, want %q", got, want)
 	}
 
-	r = resource.NewWithAttributes(attribute.String("service.name", wrong))
-	l := attribute.NewSet(attribute.String("service.name", want))
+	// The Resource service name is authoritative over labels.
+	r = resource.NewWithAttributes(attribute.String("service.name", want))
+	l := attribute.NewSet(attribute.String("service.name", wrong))
 	attrs = attributes(wrong, r, nil, &l)
 	if got, ok := attrs[serviceNameAttrKey]; !ok || got != want {
 		t.Errorf("service.name attribute wrong: got %q, want %q", got, want)
 	}
+
+	// Labels take precedence over the exporter service name.
+	l = attribute.NewSet(attribute.String("service.name", want))
+	attrs = attributes(wrong, nil, nil, &l)
+	if got, ok := attrs[serviceNameAttrKey]; !ok || got != want {
+		t.Errorf("service.name attribute wrong: got %q, want %q", got, want)
+	}
 }
 
 func TestAttributes(t *testing.T) {
@@ -64,21 +72,17 @@ func TestAttributes(t *testing.T) {
 	}{
 		{}, // test defaults
 		{
+			// Resource attributes are sent in the common block.
 			res:    resource.NewWithAttributes(attribute.String("A", "a")),
 			opts:   nil,
 			labels: nil,
-			want: map[string]interface{}{
-				"A": "a",
-			},
+			want:   map[string]interface{}{},
 		},
 		{
 			res:    resource.NewWithAttributes(attribute.String("A", "a"), attribute.Int64("1", 1)),
 			opts:   nil,
 			labels: nil,
-			want: map[string]interface{}{
-				"A": "a",
-				"1": int64(1),
-			},
+			want:   map[string]interface{}{},
 		},
 		{
 			res:    nil,
@@ -121,7 +125,6 @@ func TestAttributes(t *testing.T) {
 			},
 			labels: []attribute.KeyValue{attribute.String("K2", "V3")},
 			want: map[string]interface{}{
-				"K1":          "V1",
 				"K2":          "V3",
 				"unit":        "ms",
 				"description": "d3",
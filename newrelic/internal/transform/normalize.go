@@ -0,0 +1,181 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"strings"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+)
+
+const unitAttrKey = "unit"
+
+// unitNames are the readable names of UCUM units.
+var unitNames = map[string]string{
+	"ns":   "nanoseconds",
+	"us":   "microseconds",
+	"ms":   "milliseconds",
+	"s":    "seconds",
+	"min":  "minutes",
+	"h":    "hours",
+	"d":    "days",
+	"bit":  "bits",
+	"By":   "bytes",
+	"KBy":  "kilobytes",
+	"MBy":  "megabytes",
+	"GBy":  "gigabytes",
+	"TBy":  "terabytes",
+	"KiBy": "kibibytes",
+	"MiBy": "mebibytes",
+	"GiBy": "gibibytes",
+	"TiBy": "tebibytes",
+	"%":    "percent",
+	"Hz":   "hertz",
+	"Cel":  "celsius",
+	"1":    "",
+}
+
+// perUnitNames are the readable names of UCUM units used as a denominator,
+// e.g. "By/s".
+var perUnitNames = map[string]string{
+	"ns":  "nanosecond",
+	"us":  "microsecond",
+	"ms":  "millisecond",
+	"s":   "second",
+	"min": "minute",
+	"h":   "hour",
+	"d":   "day",
+	"By":  "byte",
+}
+
+// durationSeconds are the number of seconds in each UCUM duration unit.
+var durationSeconds = map[string]float64{
+	"ns":  1e-9,
+	"us":  1e-6,
+	"ms":  1e-3,
+	"s":   1,
+	"min": 60,
+	"h":   3600,
+	"d":   86400,
+}
+
+// MetricNormalizer normalizes metric names and units for New Relic. Names are
+// sanitized to the characters New Relic allows, letters, digits, '_', '.',
+// and ':', other characters being replaced with '_'. UCUM units, such as "ms",
+// "By", or "1", are converted to readable names, such as "milliseconds" and
+// "bytes". Dimensionless metrics have no unit.
+//
+// A nil *MetricNormalizer leaves metrics unchanged.
+type MetricNormalizer struct {
+	// Namespace prefixes metric names, separated by a '.', if not empty.
+	// Names already starting with the prefix are left unchanged.
+	Namespace string
+	// Seconds converts the values of metrics with a duration unit to
+	// seconds.
+	Seconds bool
+}
+
+// Normalize returns m with its name and unit normalized, where unit is the
+// unit of the instrument that recorded m. The unit attribute of m is only
+// replaced if unit is not empty, a "unit" label is left unchanged. The
+// attributes of m are modified in place.
+func (n *MetricNormalizer) Normalize(m telemetry.Metric, unit string) telemetry.Metric {
+	if n == nil {
+		return m
+	}
+	switch metric := m.(type) {
+	case telemetry.Count:
+		metric.Name = n.name(metric.Name)
+		scale := n.unit(metric.Attributes, unit)
+		metric.Value *= scale
+		return metric
+	case telemetry.Gauge:
+		metric.Name = n.name(metric.Name)
+		scale := n.unit(metric.Attributes, unit)
+		metric.Value *= scale
+		return metric
+	case telemetry.Summary:
+		metric.Name = n.name(metric.Name)
+		scale := n.unit(metric.Attributes, unit)
+		metric.Sum *= scale
+		metric.Min *= scale
+		metric.Max *= scale
+		return metric
+	}
+	return m
+}
+
+// name returns the sanitized metric name, prefixed with the namespace.
+func (n *MetricNormalizer) name(name string) string {
+	name = sanitizeMetricName(name)
+	if n.Namespace == "" {
+		return name
+	}
+	prefix := sanitizeMetricName(n.Namespace) + "."
+	if strings.HasPrefix(name, prefix) {
+		return name
+	}
+	return prefix + name
+}
+
+// unit replaces the unit attribute of attrs with the readable name of unit,
+// and returns the factor values are multiplied by to convert them to the new
+// unit. Attributes are unchanged if unit is empty.
+func (n *MetricNormalizer) unit(attrs map[string]interface{}, unit string) float64 {
+	if unit == "" {
+		return 1
+	}
+	scale := 1.0
+	if seconds, ok := durationSeconds[unit]; ok && n.Seconds {
+		scale = seconds
+		unit = "s"
+	}
+	if readable := readableUnit(unit); readable != "" {
+		attrs[unitAttrKey] = readable
+	} else {
+		delete(attrs, unitAttrKey)
+	}
+	return scale
+}
+
+// sanitizeMetricName returns name with the characters New Relic does not
+// allow in metric names replaced with '_'.
+func sanitizeMetricName(name string) string {
+	valid := func(r rune) bool {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' ||
+			r == '_' || r == '.' || r == ':'
+	}
+	return strings.Map(func(r rune) rune {
+		if valid(r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// readableUnit returns the readable name of a UCUM unit, e.g. "bytes/second"
+// for "By/s". Annotations are used without their braces, "{request}" is
+// "request". Unknown units are returned unchanged, dimensionless ones as "".
+func readableUnit(unit string) string {
+	i := strings.IndexByte(unit, '/')
+	if i < 0 {
+		return unitName(unit, unitNames)
+	}
+	num, den := unitName(unit[:i], unitNames), unitName(unit[i+1:], perUnitNames)
+	if num == "" {
+		return "per " + den
+	}
+	return num + "/" + den
+}
+
+// unitName returns the name of unit in names, or of the annotation unit.
+func unitName(unit string, names map[string]string) string {
+	if name, ok := names[unit]; ok {
+		return name
+	}
+	if strings.HasPrefix(unit, "{") && strings.HasSuffix(unit, "}") {
+		return unit[1 : len(unit)-1]
+	}
+	return unit
+}
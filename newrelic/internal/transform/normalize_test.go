@@ -0,0 +1,130 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+)
+
+func TestSanitizeMetricName(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want string
+	}{
+		{name: "http.server.duration", want: "http.server.duration"},
+		{name: "process:cpu_time", want: "process:cpu_time"},
+		{name: "http/server duration", want: "http_server_duration"},
+		{name: "requests-total", want: "requests_total"},
+		{name: "latência", want: "lat_ncia"},
+	} {
+		if got := sanitizeMetricName(test.name); got != test.want {
+			t.Errorf("sanitizeMetricName(%q): got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestReadableUnit(t *testing.T) {
+	for _, test := range []struct {
+		unit string
+		want string
+	}{
+		{unit: "ms", want: "milliseconds"},
+		{unit: "By", want: "bytes"},
+		{unit: "KiBy", want: "kibibytes"},
+		{unit: "1", want: ""},
+		{unit: "%", want: "percent"},
+		{unit: "{request}", want: "request"},
+		{unit: "By/s", want: "bytes/second"},
+		{unit: "{request}/s", want: "request/second"},
+		{unit: "1/s", want: "per second"},
+		{unit: "furlongs", want: "furlongs"},
+	} {
+		if got := readableUnit(test.unit); got != test.want {
+			t.Errorf("readableUnit(%q): got %q, want %q", test.unit, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeMetric(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		normalizer *MetricNormalizer
+		metric     telemetry.Metric
+		unit       string
+		want       telemetry.Metric
+	}{
+		{
+			name:   "nil normalizer",
+			metric: telemetry.Count{Name: "a b", Value: 1, Attributes: map[string]interface{}{"unit": "ms"}},
+			unit:   "ms",
+			want:   telemetry.Count{Name: "a b", Value: 1, Attributes: map[string]interface{}{"unit": "ms"}},
+		},
+		{
+			name:       "readable unit",
+			normalizer: &MetricNormalizer{},
+			metric:     telemetry.Count{Name: "a b", Value: 1, Attributes: map[string]interface{}{"unit": "ms"}},
+			unit:       "ms",
+			want:       telemetry.Count{Name: "a_b", Value: 1, Attributes: map[string]interface{}{"unit": "milliseconds"}},
+		},
+		{
+			name:       "namespace",
+			normalizer: &MetricNormalizer{Namespace: "my app"},
+			metric:     telemetry.Gauge{Name: "a", Value: 1, Attributes: map[string]interface{}{"unit": "1"}},
+			unit:       "1",
+			want:       telemetry.Gauge{Name: "my_app.a", Value: 1, Attributes: map[string]interface{}{}},
+		},
+		{
+			name:       "namespace already prefixed",
+			normalizer: &MetricNormalizer{Namespace: "app"},
+			metric:     telemetry.Gauge{Name: "app.a", Value: 1},
+			want:       telemetry.Gauge{Name: "app.a", Value: 1},
+		},
+		{
+			name:       "seconds",
+			normalizer: &MetricNormalizer{Seconds: true},
+			metric:     telemetry.Gauge{Name: "a", Value: 2, Attributes: map[string]interface{}{"unit": "min"}},
+			unit:       "min",
+			want:       telemetry.Gauge{Name: "a", Value: 120, Attributes: map[string]interface{}{"unit": "seconds"}},
+		},
+		{
+			name:       "seconds summary",
+			normalizer: &MetricNormalizer{Seconds: true},
+			metric:     telemetry.Summary{Name: "a", Count: 2, Sum: 3000, Min: 1000, Max: 2000, Attributes: map[string]interface{}{"unit": "ms"}},
+			unit:       "ms",
+			want:       telemetry.Summary{Name: "a", Count: 2, Sum: 3, Min: 1, Max: 2, Attributes: map[string]interface{}{"unit": "seconds"}},
+		},
+		{
+			name:       "seconds non-duration",
+			normalizer: &MetricNormalizer{Seconds: true},
+			metric:     telemetry.Count{Name: "a", Value: 2, Attributes: map[string]interface{}{"unit": "By"}},
+			unit:       "By",
+			want:       telemetry.Count{Name: "a", Value: 2, Attributes: map[string]interface{}{"unit": "bytes"}},
+		},
+		{
+			name:       "unit label without instrument unit",
+			normalizer: &MetricNormalizer{Seconds: true},
+			metric:     telemetry.Gauge{Name: "a", Value: 2, Attributes: map[string]interface{}{"unit": "ms"}},
+			want:       telemetry.Gauge{Name: "a", Value: 2, Attributes: map[string]interface{}{"unit": "ms"}},
+		},
+	} {
+		if got := test.normalizer.Normalize(test.metric, test.unit); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.name, got, test.want)
+		}
+	}
+
+	// Undefined summary minimums and maximums stay undefined.
+	s := (&MetricNormalizer{Seconds: true}).Normalize(telemetry.Summary{
+		Name:       "a",
+		Min:        math.NaN(),
+		Max:        math.NaN(),
+		Attributes: map[string]interface{}{"unit": "ms"},
+	}, "ms").(telemetry.Summary)
+	if !math.IsNaN(s.Min) || !math.IsNaN(s.Max) {
+		t.Errorf("summary min and max: got %v and %v, want NaN", s.Min, s.Max)
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Resource transforms the attributes of an OpenTelemetry Resource into New
+// Relic common attributes. These are sent once in the common block of a
+// payload instead of being copied into every span and metric.
+//
+// A nil map is returned if the resource has no attributes.
+func Resource(res *resource.Resource) map[string]interface{} {
+	if res.Len() == 0 {
+		return nil
+	}
+	attrs := make(map[string]interface{}, res.Len())
+	for iter := res.Iter(); iter.Next(); {
+		kv := iter.Label()
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return attrs
+}
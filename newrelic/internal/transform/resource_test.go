@@ -0,0 +1,43 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestResource(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		res  *resource.Resource
+		want map[string]interface{}
+	}{
+		{
+			name: "nil resource",
+		},
+		{
+			name: "empty resource",
+			res:  resource.Empty(),
+		},
+		{
+			name: "resource with attributes",
+			res: resource.NewWithAttributes(
+				attribute.String("service.name", "resource service"),
+				attribute.Int64("process.pid", 1),
+			),
+			want: map[string]interface{}{
+				"service.name": "resource service",
+				"process.pid":  int64(1),
+			},
+		},
+	} {
+		if got := Resource(test.res); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// semconvRename is an attribute renamed by the OpenTelemetry semantic
+// conventions.
+type semconvRename struct {
+	// from is the name used before version.
+	from attribute.Key
+	// to is the name used since version.
+	to attribute.Key
+	// version is the semantic conventions version the rename was made in.
+	version string
+}
+
+// semconvRenames are the known attribute renames. Each name appears at most
+// once so every rename can be translated in both directions.
+var semconvRenames = []semconvRename{
+	{from: "messaging.destination", to: "messaging.destination.name", version: "1.17.0"},
+	{from: "http.user_agent", to: "user_agent.original", version: "1.19.0"},
+	{from: "http.method", to: "http.request.method", version: "1.21.0"},
+	{from: "http.status_code", to: "http.response.status_code", version: "1.21.0"},
+	{from: "http.url", to: "url.full", version: "1.21.0"},
+	{from: "http.scheme", to: "url.scheme", version: "1.21.0"},
+	{from: "http.request_content_length", to: "http.request.body.size", version: "1.21.0"},
+	{from: "http.response_content_length", to: "http.response.body.size", version: "1.21.0"},
+	{from: "net.peer.name", to: "server.address", version: "1.21.0"},
+	{from: "net.peer.port", to: "server.port", version: "1.21.0"},
+	{from: "net.sock.peer.addr", to: "network.peer.address", version: "1.21.0"},
+	{from: "net.sock.peer.port", to: "network.peer.port", version: "1.21.0"},
+	{from: "net.protocol.name", to: "network.protocol.name", version: "1.21.0"},
+	{from: "net.protocol.version", to: "network.protocol.version", version: "1.21.0"},
+	{from: "net.transport", to: "network.transport", version: "1.21.0"},
+	{from: "db.name", to: "db.namespace", version: "1.26.0"},
+	{from: "db.statement", to: "db.query.text", version: "1.26.0"},
+	{from: "db.operation", to: "db.operation.name", version: "1.26.0"},
+	{from: "db.sql.table", to: "db.collection.name", version: "1.26.0"},
+}
+
+// SemconvTranslator renames span attributes and metric labels to the names
+// used by a target version of the OpenTelemetry semantic conventions, so data
+// from instrumentation using different versions can be queried the same way.
+//
+// A nil *SemconvTranslator leaves attributes unchanged.
+type SemconvTranslator struct {
+	renames map[attribute.Key]attribute.Key
+}
+
+// NewSemconvTranslator returns a SemconvTranslator that translates known
+// attributes to their names in the target semantic conventions version,
+// e.g. "1.21.0". Attributes renamed after target are translated back to
+// their older name.
+func NewSemconvTranslator(target string) (*SemconvTranslator, error) {
+	tv, err := parseSemconvVersion(target)
+	if err != nil {
+		return nil, err
+	}
+	renames := make(map[attribute.Key]attribute.Key, len(semconvRenames))
+	for _, r := range semconvRenames {
+		rv, err := parseSemconvVersion(r.version)
+		if err != nil {
+			return nil, err
+		}
+		if compareSemconvVersions(tv, rv) >= 0 {
+			renames[r.from] = r.to
+		} else {
+			renames[r.to] = r.from
+		}
+	}
+	return &SemconvTranslator{renames: renames}, nil
+}
+
+// Translate returns kvs with known attributes renamed to the target version.
+// If an attribute is present under both names the value of the target name
+// is kept. The kvs slice is not modified.
+func (t *SemconvTranslator) Translate(kvs []attribute.KeyValue) []attribute.KeyValue {
+	if t == nil {
+		return kvs
+	}
+	translate := false
+	present := make(map[attribute.Key]bool, len(kvs))
+	for _, kv := range kvs {
+		present[kv.Key] = true
+		if _, ok := t.renames[kv.Key]; ok {
+			translate = true
+		}
+	}
+	if !translate {
+		return kvs
+	}
+
+	out := make([]attribute.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		if to, ok := t.renames[kv.Key]; ok {
+			if present[to] {
+				continue
+			}
+			kv.Key = to
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+// parseSemconvVersion parses a "major.minor.patch" version. The patch and a
+// leading "v" are optional.
+func parseSemconvVersion(version string) ([3]int, error) {
+	var v [3]int
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, fmt.Errorf("invalid semantic conventions version: %q", version)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid semantic conventions version: %q", version)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// compareSemconvVersions returns -1, 0, or 1 if a is older, the same as, or
+// newer than b.
+func compareSemconvVersions(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestSemconvRenamesUnique(t *testing.T) {
+	seen := make(map[attribute.Key]bool)
+	for _, r := range semconvRenames {
+		for _, k := range []attribute.Key{r.from, r.to} {
+			if seen[k] {
+				t.Errorf("%s renamed more than once", k)
+			}
+			seen[k] = true
+		}
+		if _, err := parseSemconvVersion(r.version); err != nil {
+			t.Errorf("%s: %v", r.from, err)
+		}
+	}
+}
+
+func TestSemconvTranslatorMappings(t *testing.T) {
+	older, err := NewSemconvTranslator("1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := NewSemconvTranslator("99.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range semconvRenames {
+		t.Run(string(r.from), func(t *testing.T) {
+			at, err := NewSemconvTranslator(r.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, test := range []struct {
+				name string
+				tr   *SemconvTranslator
+				in   attribute.Key
+				want attribute.Key
+			}{
+				{name: "old to older", tr: older, in: r.from, want: r.from},
+				{name: "new to older", tr: older, in: r.to, want: r.from},
+				{name: "old to rename version", tr: at, in: r.from, want: r.to},
+				{name: "new to rename version", tr: at, in: r.to, want: r.to},
+				{name: "old to newer", tr: newer, in: r.from, want: r.to},
+			} {
+				got := test.tr.Translate([]attribute.KeyValue{test.in.String("v")})
+				want := []attribute.KeyValue{test.want.String("v")}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: got %v, want %v", test.name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSemconvTranslatorTranslate(t *testing.T) {
+	tr, err := NewSemconvTranslator("v1.21.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name string
+		in   []attribute.KeyValue
+		want []attribute.KeyValue
+	}{
+		{
+			name: "no known attributes",
+			in:   []attribute.KeyValue{attribute.String("foo", "bar")},
+			want: []attribute.KeyValue{attribute.String("foo", "bar")},
+		},
+		{
+			name: "mixed",
+			in: []attribute.KeyValue{
+				attribute.String("http.method", "GET"),
+				attribute.Int("http.status_code", 200),
+				attribute.String("foo", "bar"),
+			},
+			want: []attribute.KeyValue{
+				attribute.String("http.request.method", "GET"),
+				attribute.Int("http.response.status_code", 200),
+				attribute.String("foo", "bar"),
+			},
+		},
+		{
+			name: "both names present",
+			in: []attribute.KeyValue{
+				attribute.String("http.method", "GET"),
+				attribute.String("http.request.method", "POST"),
+			},
+			want: []attribute.KeyValue{
+				attribute.String("http.request.method", "POST"),
+			},
+		},
+	} {
+		if got := tr.Translate(test.in); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	var nilTranslator *SemconvTranslator
+	in := []attribute.KeyValue{attribute.String("http.method", "GET")}
+	if got := nilTranslator.Translate(in); !reflect.DeepEqual(got, in) {
+		t.Errorf("nil translator: got %v, want %v", got, in)
+	}
+}
+
+func TestNewSemconvTranslatorInvalidVersion(t *testing.T) {
+	for _, v := range []string{"", "1", "1.x", "1.2.3.4", "-1.0"} {
+		if _, err := NewSemconvTranslator(v); err == nil {
+			t.Errorf("%q: expected error", v)
+		}
+	}
+}
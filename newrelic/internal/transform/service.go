@@ -0,0 +1,59 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// entityGUIDKey is the Resource attribute that can be used to attribute
+// telemetry to an existing New Relic entity.
+const entityGUIDKey = attribute.Key(entityGUIDAttrKey)
+
+// Service is the resolved identity of the service that produced telemetry.
+// The same identity is used for spans and metrics so both are attributed to
+// the same New Relic entity.
+type Service struct {
+	// Name is the service name.
+	Name string
+	// EntityGUID is the GUID of the New Relic entity the service reports
+	// to, if known.
+	EntityGUID string
+}
+
+// ResolveService returns the identity of the service that produced data with
+// res. The Resource service name is authoritative, fallback is used as the
+// name if res does not contain one.
+func ResolveService(fallback string, res *resource.Resource) Service {
+	svc := Service{Name: fallback}
+	for iter := res.Iter(); iter.Next(); {
+		switch kv := iter.Label(); kv.Key {
+		case semconv.ServiceNameKey:
+			svc.Name = kv.Value.AsString()
+		case entityGUIDKey:
+			svc.EntityGUID = kv.Value.AsString()
+		}
+	}
+	return svc
+}
+
+// Attributes returns the New Relic attributes identifying the service.
+//
+// A nil map is returned if the service has no name.
+func (s Service) Attributes() map[string]interface{} {
+	if s.Name == "" {
+		return nil
+	}
+	attrs := map[string]interface{}{
+		serviceNameAttrKey: s.Name,
+		entityNameAttrKey:  s.Name,
+		entityTypeAttrKey:  entityTypeAttrValue,
+	}
+	if s.EntityGUID != "" {
+		attrs[entityGUIDAttrKey] = s.EntityGUID
+	}
+	return attrs
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestResolveService(t *testing.T) {
+	fallback := "exporter service"
+	for _, test := range []struct {
+		name string
+		res  *resource.Resource
+		want Service
+	}{
+		{
+			name: "nil resource",
+			want: Service{Name: fallback},
+		},
+		{
+			name: "resource without service name",
+			res:  resource.NewWithAttributes(attribute.String("host.name", "host")),
+			want: Service{Name: fallback},
+		},
+		{
+			name: "resource with service name",
+			res:  resource.NewWithAttributes(attribute.String("service.name", "resource service")),
+			want: Service{Name: "resource service"},
+		},
+		{
+			name: "resource with entity GUID",
+			res: resource.NewWithAttributes(
+				attribute.String("service.name", "resource service"),
+				attribute.String("entity.guid", "MXxBUE18QVBQTElDQVRJT058MQ"),
+			),
+			want: Service{Name: "resource service", EntityGUID: "MXxBUE18QVBQTElDQVRJT058MQ"},
+		},
+	} {
+		if got := ResolveService(fallback, test.res); got != test.want {
+			t.Errorf("%s: got %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestServiceAttributes(t *testing.T) {
+	if got := (Service{}).Attributes(); got != nil {
+		t.Errorf("unnamed service attributes: got %#v, want nil", got)
+	}
+
+	got := Service{Name: "service", EntityGUID: "guid"}.Attributes()
+	want := map[string]interface{}{
+		serviceNameAttrKey: "service",
+		entityNameAttrKey:  "service",
+		entityTypeAttrKey:  entityTypeAttrValue,
+		entityGUIDAttrKey:  "guid",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("service attributes: got %#v, want %#v", got, want)
+	}
+}
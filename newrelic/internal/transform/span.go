@@ -4,60 +4,119 @@
 package transform
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/semconv"
 	apitrace "go.opentelemetry.io/otel/trace"
 )
 
+// Errors of spans New Relic cannot accept as they are.
+var (
+	ErrInvalidTraceID = errors.New("invalid trace ID")
+	ErrInvalidSpanID  = errors.New("invalid span ID")
+	ErrClockSkew      = errors.New("span ends before it starts")
+)
+
+// CheckSpan returns an ErrInvalidTraceID or ErrInvalidSpanID error if span
+// has an all-zero trace or span ID, and an ErrClockSkew error if it ends
+// before it starts, because the clock was adjusted while it was active or it
+// was not ended.
+func CheckSpan(span *trace.SpanSnapshot) error {
+	if !span.SpanContext.TraceID().IsValid() {
+		return fmt.Errorf("%w: span %q", ErrInvalidTraceID, span.Name)
+	}
+	if !span.SpanContext.SpanID().IsValid() {
+		return fmt.Errorf("%w: span %q", ErrInvalidSpanID, span.Name)
+	}
+	if span.EndTime.Before(span.StartTime) {
+		return fmt.Errorf("%w: span %q started at %v and ended at %v", ErrClockSkew,
+			span.Name, span.StartTime, span.EndTime)
+	}
+	return nil
+}
+
+// RepairClockSkew returns a copy of span, which ends before it starts, with a
+// zero duration and marked with the `nr.clock_skew` attribute.
+func RepairClockSkew(span *trace.SpanSnapshot) *trace.SpanSnapshot {
+	repaired := *span
+	repaired.EndTime = span.StartTime
+	attrs := make([]attribute.KeyValue, 0, len(span.Attributes)+1)
+	repaired.Attributes = append(append(attrs, span.Attributes...), attribute.Bool(clockSkewAttrKey, true))
+	return &repaired
+}
+
 // Span transforms an OpenTelemetry SpanData into a New Relic Span for a
 // unique service.
 //
+// The service name is resolved with the precedence Resource, span
+// attributes, and then the service argument.
+//
+// The duration of spans that end before they start is set to zero and they
+// are marked with the `nr.clock_skew` attribute.
+//
 // https://godoc.org/github.com/newrelic/newrelic-telemetry-sdk-go/telemetry#Span
 // https://godoc.org/go.opentelemetry.io/otel/sdk/export/trace#SpanData
 func Span(service string, span *trace.SpanSnapshot) telemetry.Span {
-	// Default to exporter service name.
-	serviceName := service
+	// Resource service name is authoritative, the span attribute and then
+	// the exporter service name are used if it has none.
+	serviceName := ResolveService("", span.Resource).Name
+	fromResource := serviceName != ""
 
 	// Account for the instrumentation provider and collector name.
-	numAttrs := len(span.Attributes) + span.Resource.Len() + 2
+	numAttrs := len(span.Attributes) + 2
 
 	// If kind has been set, make room for it.
 	if span.SpanKind != apitrace.SpanKindUnspecified {
 		numAttrs++
 	}
 
+	// Make room for any span metadata counts that will be included.
+	counts := spanCounts(span)
+	numAttrs += len(counts)
+
 	// Status of Ok and Unset are not considered errors.
 	isError := span.StatusCode == codes.Error
 	if isError {
 		numAttrs += 2
 	}
 
-	// Copy attributes to new value.
+	// Copy attributes to new value. Resource attributes are sent in the
+	// common block of the payload instead of with every span.
 	attrs := make(map[string]interface{}, numAttrs)
-	for iter := span.Resource.Iter(); iter.Next(); {
-		kv := iter.Label()
-		// Resource service name overrides the exporter.
-		if kv.Key == semconv.ServiceNameKey {
-			serviceName = kv.Value.AsString()
-		}
-		attrs[string(kv.Key)] = kv.Value.AsInterface()
-	}
 	for _, kv := range span.Attributes {
-		// Span service name overrides the Resource.
+		// The service name is sent as a top-level field of the span,
+		// which the Trace API sends as the `service.name` attribute.
 		if kv.Key == semconv.ServiceNameKey {
-			serviceName = kv.Value.AsString()
+			if !fromResource {
+				serviceName = kv.Value.AsString()
+			}
+			continue
 		}
 		attrs[string(kv.Key)] = kv.Value.AsInterface()
 	}
 
+	if serviceName == "" {
+		serviceName = service
+	}
+
+	// Derive the attributes New Relic uses to render external and datastore
+	// calls before any are added by the exporter.
+	enrich(span.SpanKind, attrs)
+
 	if span.SpanKind != apitrace.SpanKindUnspecified {
 		attrs["span.kind"] = strings.ToLower(span.SpanKind.String())
 	}
 
+	for k, v := range counts {
+		attrs[k] = v
+	}
+
 	// New Relic registered attributes to identify where this data came from.
 	attrs[instrumentationProviderAttrKey] = instrumentationProviderAttrValue
 	attrs[collectorNameAttrKey] = collectorNameAttrValue
@@ -67,6 +126,12 @@ func Span(service string, span *trace.SpanSnapshot) telemetry.Span {
 		attrs[errorMessageAttrKey] = span.StatusMessage
 	}
 
+	duration := span.EndTime.Sub(span.StartTime)
+	if duration < 0 {
+		duration = 0
+		attrs[clockSkewAttrKey] = true
+	}
+
 	parentSpanID := ""
 	if span.Parent.SpanID().IsValid() {
 		parentSpanID = span.Parent.SpanID().String()
@@ -78,8 +143,27 @@ func Span(service string, span *trace.SpanSnapshot) telemetry.Span {
 		Timestamp:   span.StartTime,
 		Name:        span.Name,
 		ParentID:    parentSpanID,
-		Duration:    span.EndTime.Sub(span.StartTime),
+		Duration:    duration,
 		ServiceName: serviceName,
 		Attributes:  attrs,
 	}
 }
+
+// spanCounts returns the non-zero dropped and child counts of span keyed by
+// the attribute name they are exported as.
+func spanCounts(span *trace.SpanSnapshot) map[string]int {
+	counts := make(map[string]int, 4)
+	if span.DroppedAttributeCount > 0 {
+		counts[droppedAttributesCountAttrKey] = span.DroppedAttributeCount
+	}
+	if span.DroppedMessageEventCount > 0 {
+		counts[droppedEventsCountAttrKey] = span.DroppedMessageEventCount
+	}
+	if span.DroppedLinkCount > 0 {
+		counts[droppedLinksCountAttrKey] = span.DroppedLinkCount
+	}
+	if span.ChildSpanCount > 0 {
+		counts[childSpanCountAttrKey] = span.ChildSpanCount
+	}
+	return counts
+}
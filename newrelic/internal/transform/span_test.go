@@ -4,6 +4,7 @@
 package transform
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -207,7 +208,6 @@ func TestTransformSpans(t *testing.T) {
 				Duration:    2 * time.Second,
 				ServiceName: "resource service",
 				Attributes: map[string]interface{}{
-					"service.name":                 "resource service",
 					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
 					collectorNameAttrKey:           collectorNameAttrValue,
 				},
@@ -236,7 +236,6 @@ func TestTransformSpans(t *testing.T) {
 				Duration:    2 * time.Second,
 				ServiceName: "resource service",
 				Attributes: map[string]interface{}{
-					"service.name":                 "resource service",
 					"span.kind":                    "client",
 					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
 					collectorNameAttrKey:           collectorNameAttrValue,
@@ -244,7 +243,7 @@ func TestTransformSpans(t *testing.T) {
 			},
 		},
 		{
-			testname: "span with service name in attributes",
+			testname: "span with service name in resource and attributes",
 			input: &exporttrace.SpanSnapshot{
 				SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
 					TraceID: sampleTraceID,
@@ -260,6 +259,33 @@ func TestTransformSpans(t *testing.T) {
 					attribute.String("service.name", "attributes service"),
 				},
 			},
+			expect: telemetry.Span{
+				Name:        "mySpan",
+				ID:          sampleSpanIDString,
+				TraceID:     sampleTraceIDString,
+				Timestamp:   now,
+				Duration:    2 * time.Second,
+				ServiceName: "resource service",
+				Attributes: map[string]interface{}{
+					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
+					collectorNameAttrKey:           collectorNameAttrValue,
+				},
+			},
+		},
+		{
+			testname: "span with service name in attributes",
+			input: &exporttrace.SpanSnapshot{
+				SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID: sampleTraceID,
+					SpanID:  sampleSpanID,
+				}),
+				StartTime: now,
+				EndTime:   now.Add(2 * time.Second),
+				Name:      "mySpan",
+				Attributes: []attribute.KeyValue{
+					attribute.String("service.name", "attributes service"),
+				},
+			},
 			expect: telemetry.Span{
 				Name:        "mySpan",
 				ID:          sampleSpanIDString,
@@ -268,7 +294,63 @@ func TestTransformSpans(t *testing.T) {
 				Duration:    2 * time.Second,
 				ServiceName: "attributes service",
 				Attributes: map[string]interface{}{
-					"service.name":                 "attributes service",
+					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
+					collectorNameAttrKey:           collectorNameAttrValue,
+				},
+			},
+		},
+		{
+			testname: "span with dropped and child counts",
+			input: &exporttrace.SpanSnapshot{
+				SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID: sampleTraceID,
+					SpanID:  sampleSpanID,
+				}),
+				StartTime:                now,
+				EndTime:                  now.Add(2 * time.Second),
+				Name:                     "mySpan",
+				DroppedAttributeCount:    1,
+				DroppedMessageEventCount: 2,
+				DroppedLinkCount:         3,
+				ChildSpanCount:           4,
+			},
+			expect: telemetry.Span{
+				Name:        "mySpan",
+				ID:          sampleSpanIDString,
+				TraceID:     sampleTraceIDString,
+				Timestamp:   now,
+				Duration:    2 * time.Second,
+				ServiceName: service,
+				Attributes: map[string]interface{}{
+					droppedAttributesCountAttrKey:  1,
+					droppedEventsCountAttrKey:      2,
+					droppedLinksCountAttrKey:       3,
+					childSpanCountAttrKey:          4,
+					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
+					collectorNameAttrKey:           collectorNameAttrValue,
+				},
+			},
+		},
+		{
+			testname: "span ending before it starts",
+			input: &exporttrace.SpanSnapshot{
+				SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID: sampleTraceID,
+					SpanID:  sampleSpanID,
+				}),
+				StartTime: now,
+				EndTime:   now.Add(-2 * time.Second),
+				Name:      "mySpan",
+			},
+			expect: telemetry.Span{
+				Name:        "mySpan",
+				ID:          sampleSpanIDString,
+				TraceID:     sampleTraceIDString,
+				Timestamp:   now,
+				Duration:    0,
+				ServiceName: service,
+				Attributes: map[string]interface{}{
+					clockSkewAttrKey:               true,
 					instrumentationProviderAttrKey: instrumentationProviderAttrValue,
 					collectorNameAttrKey:           collectorNameAttrValue,
 				},
@@ -281,3 +363,81 @@ func TestTransformSpans(t *testing.T) {
 		}
 	}
 }
+
+func TestTransformSpanServiceNameAttribute(t *testing.T) {
+	span := &exporttrace.SpanSnapshot{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: sampleTraceID,
+			SpanID:  sampleSpanID,
+		}),
+		Name:     "mySpan",
+		Resource: resource.NewWithAttributes(attribute.String("service.name", "resource service")),
+		Attributes: []attribute.KeyValue{
+			attribute.String("service.name", "attributes service"),
+		},
+	}
+	// The Resource service name takes precedence over the span attribute.
+	got := Span("exporter service", span)
+	if want := "resource service"; got.ServiceName != want {
+		t.Errorf("span service name: got %q, want %q", got.ServiceName, want)
+	}
+	if _, ok := got.Attributes["service.name"]; ok {
+		t.Error("service.name attribute not removed")
+	}
+}
+
+func TestCheckSpan(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		name    string
+		traceID trace.TraceID
+		spanID  trace.SpanID
+		start   time.Time
+		end     time.Time
+		want    error
+	}{
+		{name: "valid", traceID: sampleTraceID, spanID: sampleSpanID, start: now, end: now.Add(time.Second)},
+		{name: "zero duration", traceID: sampleTraceID, spanID: sampleSpanID, start: now, end: now},
+		{name: "zero trace ID", spanID: sampleSpanID, start: now, end: now, want: ErrInvalidTraceID},
+		{name: "zero span ID", traceID: sampleTraceID, start: now, end: now, want: ErrInvalidSpanID},
+		{name: "clock skew", traceID: sampleTraceID, spanID: sampleSpanID, start: now, end: now.Add(-time.Millisecond), want: ErrClockSkew},
+		{name: "not ended", traceID: sampleTraceID, spanID: sampleSpanID, start: now, want: ErrClockSkew},
+	} {
+		span := &exporttrace.SpanSnapshot{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: test.traceID,
+				SpanID:  test.spanID,
+			}),
+			StartTime: test.start,
+			EndTime:   test.end,
+			Name:      test.name,
+		}
+		err := CheckSpan(span)
+		if test.want == nil && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if test.want != nil && !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestRepairClockSkew(t *testing.T) {
+	now := time.Now()
+	attrs := []attribute.KeyValue{attribute.String("key", "value")}
+	span := &exporttrace.SpanSnapshot{
+		StartTime:  now,
+		EndTime:    now.Add(-time.Second),
+		Attributes: attrs[:1:1],
+	}
+	repaired := RepairClockSkew(span)
+	if !repaired.EndTime.Equal(now) {
+		t.Errorf("end time: got %v, want %v", repaired.EndTime, now)
+	}
+	want := []attribute.KeyValue{attribute.String("key", "value"), attribute.Bool(clockSkewAttrKey, true)}
+	if !reflect.DeepEqual(repaired.Attributes, want) {
+		t.Errorf("attributes: got %v, want %v", repaired.Attributes, want)
+	}
+	if !span.EndTime.Before(span.StartTime) || len(span.Attributes) != 1 {
+		t.Errorf("span modified: %v", span)
+	}
+}
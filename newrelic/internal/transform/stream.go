@@ -0,0 +1,84 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"fmt"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	v1 "github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/com_newrelic_trace_v1"
+)
+
+// Intrinsic attributes of spans streamed to a trace observer.
+const (
+	streamTypeAttrKey      = "type"
+	streamTypeAttrValue    = "Span"
+	streamTraceIDAttrKey   = "traceId"
+	streamGUIDAttrKey      = "guid"
+	streamParentIDAttrKey  = "parentId"
+	streamNameAttrKey      = "name"
+	streamTimestampAttrKey = "timestamp"
+	streamDurationAttrKey  = "duration"
+)
+
+// StreamSpan transforms a New Relic Span into the protocol buffer Span
+// streamed to an Infinite Tracing trace observer. A stream has no common
+// block, so the common attributes are sent as agent attributes of every
+// span. The span attributes are sent as user attributes.
+func StreamSpan(span telemetry.Span, common map[string]interface{}) *v1.Span {
+	intrinsics := map[string]*v1.AttributeValue{
+		streamTypeAttrKey:      streamValue(streamTypeAttrValue),
+		streamTraceIDAttrKey:   streamValue(span.TraceID),
+		streamGUIDAttrKey:      streamValue(span.ID),
+		streamNameAttrKey:      streamValue(span.Name),
+		streamTimestampAttrKey: streamValue(span.Timestamp.UnixNano() / 1e6),
+		streamDurationAttrKey:  streamValue(span.Duration.Seconds()),
+	}
+	if span.ParentID != "" {
+		intrinsics[streamParentIDAttrKey] = streamValue(span.ParentID)
+	}
+	if span.ServiceName != "" {
+		intrinsics[serviceNameAttrKey] = streamValue(span.ServiceName)
+	}
+
+	agent := make(map[string]*v1.AttributeValue, len(common))
+	for k, v := range common {
+		agent[k] = streamValue(v)
+	}
+	user := make(map[string]*v1.AttributeValue, len(span.Attributes))
+	for k, v := range span.Attributes {
+		user[k] = streamValue(v)
+	}
+
+	return &v1.Span{
+		TraceId:         span.TraceID,
+		Intrinsics:      intrinsics,
+		UserAttributes:  user,
+		AgentAttributes: agent,
+	}
+}
+
+// streamValue returns the protocol buffer value of an attribute. Values of
+// types without a protocol buffer representation are sent as strings.
+func streamValue(v interface{}) *v1.AttributeValue {
+	switch val := v.(type) {
+	case string:
+		return &v1.AttributeValue{Value: &v1.AttributeValue_StringValue{StringValue: val}}
+	case bool:
+		return &v1.AttributeValue{Value: &v1.AttributeValue_BoolValue{BoolValue: val}}
+	case int:
+		return &v1.AttributeValue{Value: &v1.AttributeValue_IntValue{IntValue: int64(val)}}
+	case int32:
+		return &v1.AttributeValue{Value: &v1.AttributeValue_IntValue{IntValue: int64(val)}}
+	case int64:
+		return &v1.AttributeValue{Value: &v1.AttributeValue_IntValue{IntValue: val}}
+	case uint32:
+		return &v1.AttributeValue{Value: &v1.AttributeValue_IntValue{IntValue: int64(val)}}
+	case float32:
+		return &v1.AttributeValue{Value: &v1.AttributeValue_DoubleValue{DoubleValue: float64(val)}}
+	case float64:
+		return &v1.AttributeValue{Value: &v1.AttributeValue_DoubleValue{DoubleValue: val}}
+	}
+	return &v1.AttributeValue{Value: &v1.AttributeValue_StringValue{StringValue: fmt.Sprint(v)}}
+}
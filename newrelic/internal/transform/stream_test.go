@@ -0,0 +1,71 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	v1 "github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/com_newrelic_trace_v1"
+)
+
+func TestStreamSpan(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	span := telemetry.Span{
+		ID:          "00f067aa0ba902b7",
+		TraceID:     "4bf92f3577b34da6a3ce929d0e0e4736",
+		ParentID:    "53995c3f42cd8ad8",
+		Name:        "span",
+		Timestamp:   start,
+		Duration:    1500 * time.Millisecond,
+		ServiceName: "service",
+		Attributes: map[string]interface{}{
+			"string":  "value",
+			"bool":    true,
+			"int64":   int64(1),
+			"float64": 1.5,
+			"slice":   []string{"a", "b"},
+		},
+	}
+	common := map[string]interface{}{"host.name": "host"}
+
+	want := &v1.Span{
+		TraceId: "4bf92f3577b34da6a3ce929d0e0e4736",
+		Intrinsics: map[string]*v1.AttributeValue{
+			"type":         streamValue("Span"),
+			"traceId":      streamValue("4bf92f3577b34da6a3ce929d0e0e4736"),
+			"guid":         streamValue("00f067aa0ba902b7"),
+			"parentId":     streamValue("53995c3f42cd8ad8"),
+			"name":         streamValue("span"),
+			"timestamp":    streamValue(int64(1600000000000)),
+			"duration":     streamValue(1.5),
+			"service.name": streamValue("service"),
+		},
+		UserAttributes: map[string]*v1.AttributeValue{
+			"string":  {Value: &v1.AttributeValue_StringValue{StringValue: "value"}},
+			"bool":    {Value: &v1.AttributeValue_BoolValue{BoolValue: true}},
+			"int64":   {Value: &v1.AttributeValue_IntValue{IntValue: 1}},
+			"float64": {Value: &v1.AttributeValue_DoubleValue{DoubleValue: 1.5}},
+			"slice":   {Value: &v1.AttributeValue_StringValue{StringValue: "[a b]"}},
+		},
+		AgentAttributes: map[string]*v1.AttributeValue{
+			"host.name": streamValue("host"),
+		},
+	}
+	if got := StreamSpan(span, common); !proto.Equal(got, want) {
+		t.Errorf("StreamSpan: got %v, want %v", got, want)
+	}
+}
+
+func TestStreamSpanRoot(t *testing.T) {
+	got := StreamSpan(telemetry.Span{ID: "00f067aa0ba902b7", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}, nil)
+	if _, ok := got.Intrinsics[streamParentIDAttrKey]; ok {
+		t.Error("root span has a parentId intrinsic")
+	}
+	if _, ok := got.Intrinsics[serviceNameAttrKey]; ok {
+		t.Error("span without a service has a service.name intrinsic")
+	}
+}
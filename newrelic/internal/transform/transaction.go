@@ -0,0 +1,156 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+// New Relic attributes of entry spans and Transaction events.
+const (
+	entryPointAttrKey      = "nr.entryPoint"
+	transactionNameAttrKey = "transaction.name"
+	transactionTypeAttrKey = "transaction.type"
+
+	transactionEventType = "Transaction"
+
+	txnNameAttrKey       = "name"
+	txnDurationAttrKey   = "duration"
+	txnTotalTimeAttrKey  = "totalTime"
+	txnTypeAttrKey       = "transactionType"
+	txnAppNameAttrKey    = "appName"
+	txnGUIDAttrKey       = "guid"
+	txnTraceIDAttrKey    = "traceId"
+	txnParentIDAttrKey   = "parentSpanId"
+	txnErrorAttrKey      = "error"
+	txnApdexZoneAttrKey  = "nr.apdexPerfZone"
+	txnStatusCodeAttrKey = "http.statusCode"
+	txnMethodAttrKey     = "request.method"
+)
+
+// Transaction types.
+const (
+	TransactionTypeWeb   = "Web"
+	TransactionTypeOther = "Other"
+)
+
+// Apdex zones of a transaction.
+const (
+	ApdexSatisfying = "S"
+	ApdexTolerating = "T"
+	ApdexFrustrated = "F"
+)
+
+// Transaction is the New Relic APM transaction of an entry span.
+type Transaction struct {
+	// Name is the transaction name, e.g. "WebTransaction/Go/GET /users".
+	Name string
+	// Type is TransactionTypeWeb or TransactionTypeOther.
+	Type string
+}
+
+// EntryTransaction returns the transaction of span if it is the entry span of
+// a service: a root span, a span with a remote parent, or a server or
+// consumer span. Server spans with an HTTP method are web transactions.
+func EntryTransaction(span *trace.SpanSnapshot) (Transaction, bool) {
+	isEntry := !span.Parent.IsValid() || span.Parent.IsRemote() ||
+		span.SpanKind == apitrace.SpanKindServer || span.SpanKind == apitrace.SpanKindConsumer
+	if !isEntry {
+		return Transaction{}, false
+	}
+
+	if span.SpanKind == apitrace.SpanKindServer {
+		for _, kv := range span.Attributes {
+			if hasKey(semconvHTTPMethod, string(kv.Key)) {
+				return Transaction{Name: "WebTransaction/Go/" + span.Name, Type: TransactionTypeWeb}, true
+			}
+		}
+	}
+	return Transaction{Name: "OtherTransaction/Go/" + span.Name, Type: TransactionTypeOther}, true
+}
+
+// Mark adds the entry point and transaction attributes to the New Relic span
+// of the transaction entry span.
+func (t Transaction) Mark(span *telemetry.Span) {
+	if span.Attributes == nil {
+		span.Attributes = make(map[string]interface{}, 3)
+	}
+	span.Attributes[entryPointAttrKey] = true
+	span.Attributes[transactionNameAttrKey] = t.Name
+	span.Attributes[transactionTypeAttrKey] = t.Type
+}
+
+// ApdexZone returns the Apdex zone of a transaction of duration d with the
+// Apdex threshold apdexT. Errors are always frustrated.
+func ApdexZone(d, apdexT time.Duration, isError bool) string {
+	switch {
+	case isError:
+		return ApdexFrustrated
+	case d <= apdexT:
+		return ApdexSatisfying
+	case d <= 4*apdexT:
+		return ApdexTolerating
+	}
+	return ApdexFrustrated
+}
+
+// Event transforms the transaction of the entry span into a New Relic
+// Transaction event. The timing and IDs of the event are those of span, the
+// New Relic Span snap is exported as, so the event reflects any repairs made
+// to it. The Event API has no common block, so the common attributes are
+// copied into the event. The Apdex zone is only included for web
+// transactions.
+func (t Transaction) Event(span telemetry.Span, snap *trace.SpanSnapshot, common map[string]interface{}, apdexZone string) telemetry.Event {
+	attrs := make(map[string]interface{}, len(common)+12)
+	for k, v := range common {
+		attrs[k] = v
+	}
+	attrs[txnNameAttrKey] = t.Name
+	attrs[txnTypeAttrKey] = t.Type
+	attrs[txnDurationAttrKey] = span.Duration.Seconds()
+	attrs[txnTotalTimeAttrKey] = span.Duration.Seconds()
+	attrs[txnAppNameAttrKey] = span.ServiceName
+	attrs[txnGUIDAttrKey] = span.ID
+	attrs[txnTraceIDAttrKey] = span.TraceID
+	if span.ParentID != "" {
+		attrs[txnParentIDAttrKey] = span.ParentID
+	}
+	attrs[txnErrorAttrKey] = snap.StatusCode == codes.Error
+	if t.Type == TransactionTypeWeb {
+		attrs[txnApdexZoneAttrKey] = apdexZone
+		for _, kv := range snap.Attributes {
+			switch k := string(kv.Key); {
+			case hasKey(semconvHTTPStatusCode, k):
+				attrs[txnStatusCodeAttrKey] = kv.Value.AsInterface()
+			case hasKey(semconvHTTPMethod, k):
+				attrs[txnMethodAttrKey] = kv.Value.AsInterface()
+			}
+		}
+	}
+
+	// New Relic registered attributes to identify where this data came from.
+	attrs[instrumentationProviderAttrKey] = instrumentationProviderAttrValue
+	attrs[collectorNameAttrKey] = collectorNameAttrValue
+
+	return telemetry.Event{
+		EventType:  transactionEventType,
+		Timestamp:  span.Timestamp,
+		Attributes: attrs,
+	}
+}
+
+// hasKey returns true if keys contains k.
+func hasKey(keys []string, k string) bool {
+	for _, key := range keys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestEntryTransaction(t *testing.T) {
+	traceID, _ := apitrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	parentID, _ := apitrace.SpanIDFromHex("00f067aa0ba902b7")
+	local := apitrace.NewSpanContext(apitrace.SpanContextConfig{TraceID: traceID, SpanID: parentID})
+	remote := local.WithRemote(true)
+	httpMethod := []attribute.KeyValue{attribute.String("http.method", "GET")}
+
+	for _, test := range []struct {
+		name   string
+		parent apitrace.SpanContext
+		kind   apitrace.SpanKind
+		attrs  []attribute.KeyValue
+		want   Transaction
+		entry  bool
+	}{
+		{"root", apitrace.SpanContext{}, apitrace.SpanKindInternal, nil, Transaction{"OtherTransaction/Go/span", "Other"}, true},
+		{"remote parent", remote, apitrace.SpanKindInternal, nil, Transaction{"OtherTransaction/Go/span", "Other"}, true},
+		{"local server", local, apitrace.SpanKindServer, nil, Transaction{"OtherTransaction/Go/span", "Other"}, true},
+		{"local consumer", local, apitrace.SpanKindConsumer, nil, Transaction{"OtherTransaction/Go/span", "Other"}, true},
+		{"http server", remote, apitrace.SpanKindServer, httpMethod, Transaction{"WebTransaction/Go/span", "Web"}, true},
+		{"http client", local, apitrace.SpanKindClient, httpMethod, Transaction{}, false},
+		{"local internal", local, apitrace.SpanKindInternal, nil, Transaction{}, false},
+	} {
+		got, entry := EntryTransaction(&trace.SpanSnapshot{
+			Name:       "span",
+			Parent:     test.parent,
+			SpanKind:   test.kind,
+			Attributes: test.attrs,
+		})
+		if entry != test.entry || got != test.want {
+			t.Errorf("%s: got %#v, %t, want %#v, %t", test.name, got, entry, test.want, test.entry)
+		}
+	}
+}
+
+func TestTransactionMark(t *testing.T) {
+	span := telemetry.Span{}
+	Transaction{Name: "WebTransaction/Go/span", Type: TransactionTypeWeb}.Mark(&span)
+	want := map[string]interface{}{
+		"nr.entryPoint":    true,
+		"transaction.name": "WebTransaction/Go/span",
+		"transaction.type": "Web",
+	}
+	if !reflect.DeepEqual(span.Attributes, want) {
+		t.Errorf("got %#v, want %#v", span.Attributes, want)
+	}
+}
+
+func TestApdexZone(t *testing.T) {
+	apdexT := 500 * time.Millisecond
+	for _, test := range []struct {
+		d       time.Duration
+		isError bool
+		want    string
+	}{
+		{100 * time.Millisecond, false, ApdexSatisfying},
+		{500 * time.Millisecond, false, ApdexSatisfying},
+		{time.Second, false, ApdexTolerating},
+		{2 * time.Second, false, ApdexTolerating},
+		{3 * time.Second, false, ApdexFrustrated},
+		{100 * time.Millisecond, true, ApdexFrustrated},
+	} {
+		if got := ApdexZone(test.d, apdexT, test.isError); got != test.want {
+			t.Errorf("%s, error %t: got %s, want %s", test.d, test.isError, got, test.want)
+		}
+	}
+}
+
+func TestTransactionEvent(t *testing.T) {
+	traceID, _ := apitrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := apitrace.SpanIDFromHex("00f067aa0ba902b7")
+	parentID, _ := apitrace.SpanIDFromHex("b7ad6b7169203331")
+	now := time.Now()
+	snap := &trace.SpanSnapshot{
+		SpanContext: apitrace.NewSpanContext(apitrace.SpanContextConfig{TraceID: traceID, SpanID: spanID}),
+		Parent:      apitrace.NewSpanContext(apitrace.SpanContextConfig{TraceID: traceID, SpanID: parentID, Remote: true}),
+		Name:        "GET /users",
+		SpanKind:    apitrace.SpanKindServer,
+		StartTime:   now,
+		EndTime:     now.Add(2 * time.Second),
+		StatusCode:  codes.Error,
+		Attributes: []attribute.KeyValue{
+			attribute.String("http.method", "GET"),
+			attribute.Int("http.status_code", 500),
+		},
+	}
+	txn, _ := EntryTransaction(snap)
+	got := txn.Event(Span("service", snap), snap, map[string]interface{}{"host.name": "host"}, ApdexFrustrated)
+	want := telemetry.Event{
+		EventType: "Transaction",
+		Timestamp: now,
+		Attributes: map[string]interface{}{
+			"host.name":                "host",
+			"name":                     "WebTransaction/Go/GET /users",
+			"transactionType":          "Web",
+			"duration":                 2.0,
+			"totalTime":                2.0,
+			"appName":                  "service",
+			"guid":                     "00f067aa0ba902b7",
+			"traceId":                  "4bf92f3577b34da6a3ce929d0e0e4736",
+			"parentSpanId":             "b7ad6b7169203331",
+			"error":                    true,
+			"nr.apdexPerfZone":         "F",
+			"http.statusCode":          int64(500),
+			"request.method":           "GET",
+			"instrumentation.provider": "opentelemetry",
+			"collector.name":           "newrelic-opentelemetry-exporter",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+)
+
+// Limits of the New Relic ingest APIs.
+const (
+	maxAttributes            = 254
+	maxAttributeNameLength   = 255
+	maxAttributeValueLength  = 4095
+	maxMetricNameLength      = 255
+	maxSpanIDLength          = 16
+	maxTraceIDLength         = 32
+	maxTimestampAge          = 48 * time.Hour
+	maxTimestampFutureOffset = 24 * time.Hour
+)
+
+// ErrInvalid is returned for data New Relic would not accept.
+var ErrInvalid = errors.New("invalid data")
+
+// Validator checks spans and metrics against the rules of the New Relic
+// ingest APIs before they are recorded. Data that breaks them is silently
+// discarded by New Relic, in part or whole.
+//
+// A strict Validator returns an error for any violation. A lenient Validator
+// repairs what it can: negative durations and intervals are set to zero,
+// out of range timestamps to the current time, non-scalar attribute values
+// are converted to strings, overlong attribute values and metric names are
+// truncated, and attributes over the limits are removed. It returns an error
+// for what it cannot repair, such as invalid IDs or non-finite values.
+type Validator struct {
+	Strict bool
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+// Validation is the outcome of validating a span or metric.
+type Validation struct {
+	// Repairs is the number of violations repaired by a lenient Validator.
+	Repairs int
+}
+
+func (v Validator) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+// violation returns the error of a violation a lenient Validator can repair,
+// or records the repair and returns nil.
+func (v Validator) violation(res *Validation, format string, args ...interface{}) error {
+	if v.Strict {
+		return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+	}
+	res.Repairs++
+	return nil
+}
+
+// Span validates s, repairing it in place unless v is strict.
+func (v Validator) Span(s *telemetry.Span) (Validation, error) {
+	var res Validation
+	if err := validID(s.ID, maxSpanIDLength); err != nil {
+		return res, fmt.Errorf("%w: span id %q: %v", ErrInvalid, s.ID, err)
+	}
+	if err := validID(s.TraceID, maxTraceIDLength); err != nil {
+		return res, fmt.Errorf("%w: span %s: trace id %q: %v", ErrInvalid, s.ID, s.TraceID, err)
+	}
+	if s.ParentID != "" {
+		if err := validID(s.ParentID, maxSpanIDLength); err != nil {
+			return res, fmt.Errorf("%w: span %s: parent id %q: %v", ErrInvalid, s.ID, s.ParentID, err)
+		}
+	}
+
+	now := v.now()
+	if !validTimestamp(s.Timestamp, now) {
+		if err := v.violation(&res, "span %s: timestamp %v out of range", s.ID, s.Timestamp); err != nil {
+			return res, err
+		}
+		s.Timestamp = now
+	}
+	if s.Duration < 0 {
+		if err := v.violation(&res, "span %s: negative duration %v", s.ID, s.Duration); err != nil {
+			return res, err
+		}
+		s.Duration = 0
+	}
+
+	attrs, err := v.attributes(&res, s.Attributes)
+	if err != nil {
+		return res, fmt.Errorf("span %s: %w", s.ID, err)
+	}
+	s.Attributes = attrs
+	return res, nil
+}
+
+// Metric validates m and returns it, repaired unless v is strict.
+func (v Validator) Metric(m telemetry.Metric) (telemetry.Metric, Validation, error) {
+	var res Validation
+	now := v.now()
+	switch metric := m.(type) {
+	case telemetry.Count:
+		name, err := v.metricName(&res, metric.Name)
+		if err != nil {
+			return m, res, err
+		}
+		metric.Name = name
+		if err := finite(metric.Name, metric.Value); err != nil {
+			return m, res, err
+		}
+		if metric.Timestamp, err = v.metricTimestamp(&res, metric.Name, metric.Timestamp, now); err != nil {
+			return m, res, err
+		}
+		if metric.Interval, err = v.interval(&res, metric.Name, metric.Interval); err != nil {
+			return m, res, err
+		}
+		if metric.Attributes, err = v.attributes(&res, metric.Attributes); err != nil {
+			return m, res, fmt.Errorf("metric %s: %w", metric.Name, err)
+		}
+		return metric, res, nil
+	case telemetry.Gauge:
+		name, err := v.metricName(&res, metric.Name)
+		if err != nil {
+			return m, res, err
+		}
+		metric.Name = name
+		if err := finite(metric.Name, metric.Value); err != nil {
+			return m, res, err
+		}
+		if metric.Timestamp, err = v.metricTimestamp(&res, metric.Name, metric.Timestamp, now); err != nil {
+			return m, res, err
+		}
+		if metric.Attributes, err = v.attributes(&res, metric.Attributes); err != nil {
+			return m, res, fmt.Errorf("metric %s: %w", metric.Name, err)
+		}
+		return metric, res, nil
+	case telemetry.Summary:
+		name, err := v.metricName(&res, metric.Name)
+		if err != nil {
+			return m, res, err
+		}
+		metric.Name = name
+		if err := finite(metric.Name, metric.Count, metric.Sum); err != nil {
+			return m, res, err
+		}
+		if metric.Count < 0 {
+			return m, res, fmt.Errorf("%w: metric %s: negative count %v", ErrInvalid, metric.Name, metric.Count)
+		}
+		if metric.Timestamp, err = v.metricTimestamp(&res, metric.Name, metric.Timestamp, now); err != nil {
+			return m, res, err
+		}
+		if metric.Interval, err = v.interval(&res, metric.Name, metric.Interval); err != nil {
+			return m, res, err
+		}
+		if metric.Attributes, err = v.attributes(&res, metric.Attributes); err != nil {
+			return m, res, fmt.Errorf("metric %s: %w", metric.Name, err)
+		}
+		return metric, res, nil
+	}
+	return m, res, nil
+}
+
+// metricName validates the metric name.
+func (v Validator) metricName(res *Validation, name string) (string, error) {
+	if name == "" {
+		return name, fmt.Errorf("%w: empty metric name", ErrInvalid)
+	}
+	if !utf8.ValidString(name) {
+		if err := v.violation(res, "metric name %q is not valid UTF-8", name); err != nil {
+			return name, err
+		}
+		name = strings.ToValidUTF8(name, "\uFFFD")
+	}
+	if len(name) > maxMetricNameLength {
+		if err := v.violation(res, "metric name %q longer than %d bytes", name, maxMetricNameLength); err != nil {
+			return name, err
+		}
+		name = truncate(name, maxMetricNameLength)
+	}
+	return name, nil
+}
+
+// metricTimestamp validates the timestamp of a metric. Metrics without a
+// timestamp use that of the harvest.
+func (v Validator) metricTimestamp(res *Validation, name string, ts, now time.Time) (time.Time, error) {
+	if ts.IsZero() || validTimestamp(ts, now) {
+		return ts, nil
+	}
+	if err := v.violation(res, "metric %s: timestamp %v out of range", name, ts); err != nil {
+		return ts, err
+	}
+	return now, nil
+}
+
+// interval validates the interval of a metric.
+func (v Validator) interval(res *Validation, name string, interval time.Duration) (time.Duration, error) {
+	if interval >= 0 {
+		return interval, nil
+	}
+	if err := v.violation(res, "metric %s: negative interval %v", name, interval); err != nil {
+		return interval, err
+	}
+	return 0, nil
+}
+
+// attributes validates attrs and returns them, repaired unless v is strict.
+// attrs are returned as they are if valid.
+func (v Validator) attributes(res *Validation, attrs map[string]interface{}) (map[string]interface{}, error) {
+	repairs := res.Repairs
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	// Attributes over the limit are removed in name order.
+	sort.Strings(keys)
+
+	repaired := make(map[string]interface{}, len(attrs))
+	for _, k := range keys {
+		value := attrs[k]
+		if k == "" || len(k) > maxAttributeNameLength {
+			if err := v.violation(res, "invalid attribute name %q", k); err != nil {
+				return attrs, err
+			}
+			continue
+		}
+		if len(repaired) == maxAttributes {
+			if err := v.violation(res, "more than %d attributes", maxAttributes); err != nil {
+				return attrs, err
+			}
+			continue
+		}
+		switch val := value.(type) {
+		case float64:
+			if math.IsNaN(val) || math.IsInf(val, 0) {
+				if err := v.violation(res, "attribute %q: non-finite value %v", k, val); err != nil {
+					return attrs, err
+				}
+				continue
+			}
+		case float32:
+			if math.IsNaN(float64(val)) || math.IsInf(float64(val), 0) {
+				if err := v.violation(res, "attribute %q: non-finite value %v", k, val); err != nil {
+					return attrs, err
+				}
+				continue
+			}
+		case string:
+			if len(val) > maxAttributeValueLength {
+				if err := v.violation(res, "attribute %q: value longer than %d bytes", k, maxAttributeValueLength); err != nil {
+					return attrs, err
+				}
+				value = truncate(val, maxAttributeValueLength)
+			}
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		default:
+			if err := v.violation(res, "attribute %q: non-scalar value %v", k, val); err != nil {
+				return attrs, err
+			}
+			value = truncate(stringValue(val), maxAttributeValueLength)
+		}
+		repaired[k] = value
+	}
+	if res.Repairs == repairs {
+		return attrs, nil
+	}
+	return repaired, nil
+}
+
+// validID returns an error if id is not a non-zero hexadecimal ID of at most
+// maxLength characters.
+func validID(id string, maxLength int) error {
+	if id == "" {
+		return errors.New("empty")
+	}
+	if len(id) > maxLength {
+		return fmt.Errorf("longer than %d characters", maxLength)
+	}
+	zero := true
+	for _, c := range id {
+		switch {
+		case c == '0':
+		case '1' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+			zero = false
+		default:
+			return errors.New("not hexadecimal")
+		}
+	}
+	if zero {
+		return errors.New("all zero")
+	}
+	return nil
+}
+
+// validTimestamp returns if New Relic accepts data with timestamp ts at now.
+func validTimestamp(ts, now time.Time) bool {
+	return !ts.Before(now.Add(-maxTimestampAge)) && !ts.After(now.Add(maxTimestampFutureOffset))
+}
+
+// finite returns an error if any of the values of the metric name is NaN or
+// infinite. New Relic cannot encode them.
+func finite(name string, values ...float64) error {
+	for _, f := range values {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("%w: metric %s: non-finite value %v", ErrInvalid, name, f)
+		}
+	}
+	return nil
+}
+
+// stringValue returns the JSON encoding of a non-scalar value, or its default
+// format if it has none.
+func stringValue(v interface{}) string {
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// truncate returns s truncated to at most n bytes without splitting a UTF-8
+// encoded character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+)
+
+var validationNow = time.Unix(1600000000, 0)
+
+func validSpan() telemetry.Span {
+	return telemetry.Span{
+		ID:         "00f067aa0ba902b7",
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		ParentID:   "53995c3f42cd8ad8",
+		Timestamp:  validationNow.Add(-time.Minute),
+		Duration:   time.Second,
+		Attributes: map[string]interface{}{"a": "b", "n": int64(1), "f": 1.5, "ok": true},
+	}
+}
+
+func TestValidateSpan(t *testing.T) {
+	longValue := strings.Repeat("x", maxAttributeValueLength+10)
+	tooMany := make(map[string]interface{}, maxAttributes+1)
+	for i := 0; i <= maxAttributes; i++ {
+		tooMany[strings.Repeat("k", i+1)] = i
+	}
+
+	for _, test := range []struct {
+		name    string
+		modify  func(*telemetry.Span)
+		repairs int
+		// dropped is true if the span cannot be repaired.
+		dropped bool
+		check   func(*testing.T, telemetry.Span)
+	}{
+		{
+			name:   "valid",
+			modify: func(*telemetry.Span) {},
+		},
+		{
+			name:    "empty span id",
+			modify:  func(s *telemetry.Span) { s.ID = "" },
+			dropped: true,
+		},
+		{
+			name:    "zero trace id",
+			modify:  func(s *telemetry.Span) { s.TraceID = "00000000000000000000000000000000" },
+			dropped: true,
+		},
+		{
+			name:    "non-hex parent id",
+			modify:  func(s *telemetry.Span) { s.ParentID = "parent" },
+			dropped: true,
+		},
+		{
+			name:    "long span id",
+			modify:  func(s *telemetry.Span) { s.ID = "00f067aa0ba902b700" },
+			dropped: true,
+		},
+		{
+			name:    "negative duration",
+			modify:  func(s *telemetry.Span) { s.Duration = -time.Second },
+			repairs: 1,
+			check: func(t *testing.T, s telemetry.Span) {
+				if s.Duration != 0 {
+					t.Errorf("duration: got %v, want 0", s.Duration)
+				}
+			},
+		},
+		{
+			name:    "old timestamp",
+			modify:  func(s *telemetry.Span) { s.Timestamp = validationNow.Add(-72 * time.Hour) },
+			repairs: 1,
+			check: func(t *testing.T, s telemetry.Span) {
+				if !s.Timestamp.Equal(validationNow) {
+					t.Errorf("timestamp: got %v, want %v", s.Timestamp, validationNow)
+				}
+			},
+		},
+		{
+			name:    "future timestamp",
+			modify:  func(s *telemetry.Span) { s.Timestamp = validationNow.Add(48 * time.Hour) },
+			repairs: 1,
+		},
+		{
+			name: "invalid attributes",
+			modify: func(s *telemetry.Span) {
+				s.Attributes["slice"] = [2]int64{1, 2}
+				s.Attributes["nan"] = math.NaN()
+				s.Attributes["long"] = longValue
+				s.Attributes[""] = "empty"
+			},
+			repairs: 4,
+			check: func(t *testing.T, s telemetry.Span) {
+				want := map[string]interface{}{
+					"a":     "b",
+					"n":     int64(1),
+					"f":     1.5,
+					"ok":    true,
+					"slice": "[1,2]",
+					"long":  longValue[:maxAttributeValueLength],
+				}
+				if !reflect.DeepEqual(s.Attributes, want) {
+					t.Errorf("attributes: got %v, want %v", s.Attributes, want)
+				}
+			},
+		},
+		{
+			name:    "too many attributes",
+			modify:  func(s *telemetry.Span) { s.Attributes = tooMany },
+			repairs: 1,
+			check: func(t *testing.T, s telemetry.Span) {
+				if len(s.Attributes) != maxAttributes {
+					t.Errorf("expected %d attributes, got %d", maxAttributes, len(s.Attributes))
+				}
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, strict := range []bool{false, true} {
+				v := Validator{Strict: strict, Now: func() time.Time { return validationNow }}
+				s := validSpan()
+				test.modify(&s)
+				res, err := v.Span(&s)
+
+				invalid := test.dropped || (strict && test.repairs > 0)
+				if invalid {
+					if !errors.Is(err, ErrInvalid) {
+						t.Errorf("strict %t: expected ErrInvalid, got %v", strict, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("strict %t: unexpected error: %v", strict, err)
+				}
+				if res.Repairs != test.repairs {
+					t.Errorf("repairs: got %d, want %d", res.Repairs, test.repairs)
+				}
+				if test.check != nil {
+					test.check(t, s)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateMetric(t *testing.T) {
+	v := Validator{Now: func() time.Time { return validationNow }}
+	strict := Validator{Strict: true, Now: v.Now}
+
+	for _, test := range []struct {
+		name    string
+		metric  telemetry.Metric
+		want    telemetry.Metric
+		repairs int
+		dropped bool
+	}{
+		{
+			name:   "valid count",
+			metric: telemetry.Count{Name: "c", Value: 1, Interval: time.Second},
+			want:   telemetry.Count{Name: "c", Value: 1, Interval: time.Second},
+		},
+		{
+			name:   "valid summary",
+			metric: telemetry.Summary{Name: "s", Count: 1, Sum: 2, Min: math.NaN(), Max: math.NaN()},
+		},
+		{
+			name:    "empty name",
+			metric:  telemetry.Gauge{Value: 1},
+			dropped: true,
+		},
+		{
+			name:    "NaN gauge",
+			metric:  telemetry.Gauge{Name: "g", Value: math.NaN()},
+			dropped: true,
+		},
+		{
+			name:    "infinite count",
+			metric:  telemetry.Count{Name: "c", Value: math.Inf(1)},
+			dropped: true,
+		},
+		{
+			name:    "negative summary count",
+			metric:  telemetry.Summary{Name: "s", Count: -1},
+			dropped: true,
+		},
+		{
+			name:    "negative interval",
+			metric:  telemetry.Count{Name: "c", Value: 1, Interval: -time.Second},
+			want:    telemetry.Count{Name: "c", Value: 1},
+			repairs: 1,
+		},
+		{
+			name:    "long name",
+			metric:  telemetry.Gauge{Name: strings.Repeat("m", maxMetricNameLength+1), Value: 1, Timestamp: validationNow},
+			want:    telemetry.Gauge{Name: strings.Repeat("m", maxMetricNameLength), Value: 1, Timestamp: validationNow},
+			repairs: 1,
+		},
+		{
+			name:    "old timestamp",
+			metric:  telemetry.Gauge{Name: "g", Value: 1, Timestamp: validationNow.Add(-72 * time.Hour)},
+			want:    telemetry.Gauge{Name: "g", Value: 1, Timestamp: validationNow},
+			repairs: 1,
+		},
+		{
+			name:    "map attribute",
+			metric:  telemetry.Gauge{Name: "g", Value: 1, Attributes: map[string]interface{}{"m": map[string]int{"a": 1}}},
+			want:    telemetry.Gauge{Name: "g", Value: 1, Attributes: map[string]interface{}{"m": `{"a":1}`}},
+			repairs: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, res, err := v.Metric(test.metric)
+			if test.dropped {
+				if !errors.Is(err, ErrInvalid) {
+					t.Errorf("expected ErrInvalid, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Repairs != test.repairs {
+				t.Errorf("repairs: got %d, want %d", res.Repairs, test.repairs)
+			}
+			if test.want != nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+
+			_, _, err = strict.Metric(test.metric)
+			if test.repairs > 0 && !errors.Is(err, ErrInvalid) {
+				t.Errorf("strict: expected ErrInvalid, got %v", err)
+			} else if test.repairs == 0 && err != nil {
+				t.Errorf("strict: unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{s: "abc", n: 5, want: "abc"},
+		{s: "abc", n: 2, want: "ab"},
+		{s: "aé", n: 2, want: "a"},
+		{s: "aé", n: 3, want: "aé"},
+	} {
+		if got := truncate(test.s, test.n); got != test.want {
+			t.Errorf("truncate(%q, %d): got %q, want %q", test.s, test.n, got, test.want)
+		}
+	}
+}
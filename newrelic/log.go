@@ -0,0 +1,285 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/transform"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Severity is the severity of a log message. The values match the levels of
+// the log/slog package.
+type Severity int
+
+// Log message severities.
+const (
+	SeverityDebug Severity = -4
+	SeverityInfo  Severity = 0
+	SeverityWarn  Severity = 4
+	SeverityError Severity = 8
+)
+
+// String returns the name of the severity as sent to New Relic.
+func (s Severity) String() string {
+	switch {
+	case s < SeverityInfo:
+		return "DEBUG"
+	case s < SeverityWarn:
+		return "INFO"
+	case s < SeverityError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// LogRecord is a structured log message.
+type LogRecord struct {
+	// Message is the log message. It is required.
+	Message string
+	// Severity is the severity of the message.
+	Severity Severity
+	// Timestamp is the time the message was logged. The time the message is
+	// recorded is used if it is unset.
+	Timestamp time.Time
+	// Attributes describe the message.
+	Attributes []attribute.KeyValue
+}
+
+var errLogMessageEmpty = errors.New("log message is required")
+
+// RecordLog records a log message to be sent to the New Relic Log API. The
+// message is linked to the span active in ctx, if any, with `trace.id` and
+// `span.id` attributes. It is sent with the Resource and service of the
+// Exporter.
+func (e *Exporter) RecordLog(ctx context.Context, r LogRecord) error {
+	if nil == e {
+		return nil
+	}
+	if r.Message == "" {
+		return errLogMessageEmpty
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+
+	e.recording.RLock()
+	defer e.recording.RUnlock()
+	h, err := e.routedHarvesterFor(e.resource, r.Attributes)
+	if err != nil {
+		return err
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	return h.RecordLog(transform.Log(r.Message, r.Severity.String(), r.Timestamp, r.Attributes, sc))
+}
+
+// Logger records structured log messages with an Exporter in the style of
+// the log/slog package. Arguments following the message are either
+// attribute.KeyValue values or alternating keys and values.
+//
+// Only the Log method and the methods with a Context suffix link messages to
+// the span in their context with the `trace.id` and `span.id` attributes.
+// Debug, Info, Warn, and Error record messages without a span.
+type Logger struct {
+	exporter *Exporter
+	attrs    []attribute.KeyValue
+}
+
+// NewLogger returns a Logger that records log messages with e.
+func NewLogger(e *Exporter) *Logger {
+	return &Logger{exporter: e}
+}
+
+// With returns a Logger that includes the attributes of args in every log
+// message.
+func (l *Logger) With(args ...interface{}) *Logger {
+	attrs := make([]attribute.KeyValue, 0, len(l.attrs)+len(args))
+	attrs = append(attrs, l.attrs...)
+	return &Logger{
+		exporter: l.exporter,
+		attrs:    append(attrs, argsToAttributes(args)...),
+	}
+}
+
+// Log records a log message with severity. Errors are sent to the
+// OpenTelemetry error handler.
+func (l *Logger) Log(ctx context.Context, severity Severity, msg string, args ...interface{}) {
+	attrs := make([]attribute.KeyValue, 0, len(l.attrs)+len(args))
+	attrs = append(attrs, l.attrs...)
+	err := l.exporter.RecordLog(ctx, LogRecord{
+		Message:    msg,
+		Severity:   severity,
+		Attributes: append(attrs, argsToAttributes(args)...),
+	})
+	if err != nil {
+		otel.Handle(err)
+	}
+}
+
+// Debug records a debug log message that is not linked to a span. Use
+// DebugContext to link it to the span in a context.
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.Log(context.Background(), SeverityDebug, msg, args...)
+}
+
+// DebugContext records a debug log message linked to the span in ctx.
+func (l *Logger) DebugContext(ctx context.Context, msg string, args ...interface{}) {
+	l.Log(ctx, SeverityDebug, msg, args...)
+}
+
+// Info records an informational log message that is not linked to a span. Use
+// InfoContext to link it to the span in a context.
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.Log(context.Background(), SeverityInfo, msg, args...)
+}
+
+// InfoContext records an informational log message linked to the span in
+// ctx.
+func (l *Logger) InfoContext(ctx context.Context, msg string, args ...interface{}) {
+	l.Log(ctx, SeverityInfo, msg, args...)
+}
+
+// Warn records a warning log message that is not linked to a span. Use
+// WarnContext to link it to the span in a context.
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.Log(context.Background(), SeverityWarn, msg, args...)
+}
+
+// WarnContext records a warning log message linked to the span in ctx.
+func (l *Logger) WarnContext(ctx context.Context, msg string, args ...interface{}) {
+	l.Log(ctx, SeverityWarn, msg, args...)
+}
+
+// Error records an error log message that is not linked to a span. Use
+// ErrorContext to link it to the span in a context.
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.Log(context.Background(), SeverityError, msg, args...)
+}
+
+// ErrorContext records an error log message linked to the span in ctx.
+func (l *Logger) ErrorContext(ctx context.Context, msg string, args ...interface{}) {
+	l.Log(ctx, SeverityError, msg, args...)
+}
+
+// badKey is the key used for a value without a key, as in log/slog.
+const badKey = "!BADKEY"
+
+// argsToAttributes converts slog style arguments into attributes.
+func argsToAttributes(args []interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(args))
+	for len(args) > 0 {
+		switch a := args[0].(type) {
+		case attribute.KeyValue:
+			attrs = append(attrs, a)
+			args = args[1:]
+		case string:
+			if len(args) == 1 {
+				attrs = append(attrs, attribute.String(badKey, a))
+				args = args[1:]
+				continue
+			}
+			attrs = append(attrs, attributeValue(a, args[1]))
+			args = args[2:]
+		default:
+			attrs = append(attrs, attributeValue(badKey, a))
+			args = args[1:]
+		}
+	}
+	return attrs
+}
+
+// attributeValue returns an attribute for key and the arbitrary value v.
+func attributeValue(key string, v interface{}) attribute.KeyValue {
+	k := attribute.Key(key)
+	switch v := v.(type) {
+	case string:
+		return k.String(v)
+	case bool:
+		return k.Bool(v)
+	case int:
+		return k.Int(v)
+	case int8:
+		return k.Int64(int64(v))
+	case int16:
+		return k.Int64(int64(v))
+	case int32:
+		return k.Int64(int64(v))
+	case int64:
+		return k.Int64(v)
+	case uint:
+		return uintAttribute(k, uint64(v))
+	case uint8:
+		return k.Int64(int64(v))
+	case uint16:
+		return k.Int64(int64(v))
+	case uint32:
+		return k.Int64(int64(v))
+	case uint64:
+		return uintAttribute(k, v)
+	case uintptr:
+		return uintAttribute(k, uint64(v))
+	case float32:
+		return k.Float64(float64(v))
+	case float64:
+		return k.Float64(v)
+	case time.Duration:
+		return k.String(v.String())
+	case error:
+		return k.String(v.Error())
+	case fmt.Stringer:
+		return k.String(v.String())
+	default:
+		return k.String(fmt.Sprint(v))
+	}
+}
+
+// uintAttribute returns an integer attribute for key and v, or a string
+// attribute if v overflows an int64.
+func uintAttribute(k attribute.Key, v uint64) attribute.KeyValue {
+	if v > math.MaxInt64 {
+		return k.String(strconv.FormatUint(v, 10))
+	}
+	return k.Int64(int64(v))
+}
+
+// logWriter records each write as a log message.
+type logWriter struct {
+	exporter *Exporter
+	severity Severity
+}
+
+// NewLogWriter returns a writer that records each write as a log message
+// with severity. Use it as the output of a standard library log.Logger to
+// send its messages to New Relic:
+//
+//    log.SetOutput(newrelic.NewLogWriter(exporter, newrelic.SeverityInfo))
+func NewLogWriter(e *Exporter, severity Severity) io.Writer {
+	return &logWriter{exporter: e, severity: severity}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	if msg == "" {
+		return len(p), nil
+	}
+	err := w.exporter.RecordLog(context.Background(), LogRecord{
+		Message:  msg,
+		Severity: w.severity,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
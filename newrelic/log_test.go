@@ -0,0 +1,149 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+// logResource is the Resource of the logs recorded by tests.
+var logResource = resource.NewWithAttributes(semconv.ServiceNameKey.String("log-service"))
+
+func TestRecordLog(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "", mockt, WithResource(logResource))
+
+	ctx := apitrace.ContextWithSpanContext(context.Background(), testSpanContext())
+	err := e.RecordLog(ctx, LogRecord{
+		Message:    "message",
+		Severity:   SeverityWarn,
+		Attributes: []attribute.KeyValue{attribute.String("user", "gopher")},
+	})
+	if err != nil {
+		t.Fatalf("recording log: %v", err)
+	}
+	if err := e.RecordLog(ctx, LogRecord{}); err != errLogMessageEmpty {
+		t.Errorf("recording empty log: got %v, want %v", err, errLogMessageEmpty)
+	}
+	e.harvestNow(ctx)
+
+	if got := len(mockt.Data); got != 1 {
+		t.Fatalf("expecting 1 payload, got %d", got)
+	}
+	if got := mockt.Data[0].Common.Attributes["service.name"]; got != "log-service" {
+		t.Errorf("common service name: got %q, want %q", got, "log-service")
+	}
+	logs := mockt.Logs()
+	if got := len(logs); got != 1 {
+		t.Fatalf("expecting 1 log, got %d", got)
+	}
+	want := map[string]interface{}{
+		"user":                     "gopher",
+		"level":                    "WARN",
+		"trace.id":                 testTraceID.String(),
+		"span.id":                  testSpanID.String(),
+		"instrumentation.provider": "opentelemetry",
+		"collector.name":           "newrelic-opentelemetry-exporter",
+	}
+	if got := logs[0].Attributes; !reflect.DeepEqual(got, want) {
+		t.Errorf("log attributes: got %v, want %v", got, want)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "", mockt, WithResource(logResource))
+
+	logger := NewLogger(e).With("component", "checkout")
+	logger.Info("info", "count", 2, attribute.Bool("ok", true), "dangling")
+	logger.Error("error", "err", errors.New("failed"))
+	e.harvestNow(context.Background())
+
+	logs := mockt.Logs()
+	if got := len(logs); got != 2 {
+		t.Fatalf("expecting 2 logs, got %d", got)
+	}
+	for _, test := range []struct {
+		log  Log
+		want map[string]interface{}
+	}{
+		{
+			log: logs[0],
+			want: map[string]interface{}{
+				"component": "checkout",
+				"count":     float64(2),
+				"ok":        true,
+				badKey:      "dangling",
+				"level":     "INFO",
+			},
+		},
+		{
+			log: logs[1],
+			want: map[string]interface{}{
+				"component": "checkout",
+				"err":       "failed",
+				"level":     "ERROR",
+			},
+		},
+	} {
+		for k, want := range test.want {
+			if got := test.log.Attributes[k]; got != want {
+				t.Errorf("%s: attribute %s: got %v, want %v", test.log.Message, k, got, want)
+			}
+		}
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "", mockt, WithResource(logResource))
+
+	logger := log.New(NewLogWriter(e, SeverityError), "prefix: ", 0)
+	logger.Println("standard log message")
+	e.harvestNow(context.Background())
+
+	logs := mockt.Logs()
+	if got := len(logs); got != 1 {
+		t.Fatalf("expecting 1 log, got %d", got)
+	}
+	if got, want := logs[0].Message, "prefix: standard log message"; got != want {
+		t.Errorf("log message: got %q, want %q", got, want)
+	}
+	if got, want := logs[0].Attributes["level"], "ERROR"; got != want {
+		t.Errorf("log level: got %q, want %q", got, want)
+	}
+}
+
+func TestAttributeValue(t *testing.T) {
+	for _, test := range []struct {
+		value interface{}
+		want  attribute.Value
+	}{
+		{value: int8(-8), want: attribute.Int64Value(-8)},
+		{value: int16(-16), want: attribute.Int64Value(-16)},
+		{value: int32(-32), want: attribute.Int64Value(-32)},
+		{value: int64(-64), want: attribute.Int64Value(-64)},
+		{value: uint(1), want: attribute.Int64Value(1)},
+		{value: uint8(8), want: attribute.Int64Value(8)},
+		{value: uint16(16), want: attribute.Int64Value(16)},
+		{value: uint32(32), want: attribute.Int64Value(32)},
+		{value: uint64(64), want: attribute.Int64Value(64)},
+		{value: uintptr(7), want: attribute.Int64Value(7)},
+		{value: uint64(math.MaxUint64), want: attribute.StringValue("18446744073709551615")},
+	} {
+		if got := attributeValue("key", test.value).Value; got != test.want {
+			t.Errorf("attributeValue(%T(%v)): got %v, want %v", test.value, test.value, got.Emit(), test.want.Emit())
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"reflect"
+	"testing"
+)
+
+// AssertNoErrors fails t if s rejected any payload as invalid.
+func (s *Server) AssertNoErrors(t testing.TB) {
+	t.Helper()
+	for _, err := range s.Errors() {
+		t.Errorf("invalid payload: %v", err)
+	}
+}
+
+// AssertSpan fails t if s has not received a span named name with the attrs,
+// and returns the first such span.
+func (s *Server) AssertSpan(t testing.TB, name string, attrs map[string]interface{}) Span {
+	t.Helper()
+	spans := s.Spans()
+	for _, span := range spans {
+		if span.Name == name && hasAttributes(span.Attributes, attrs) {
+			return span
+		}
+	}
+	t.Errorf("no span %q with attributes %v in %d spans", name, attrs, len(spans))
+	return Span{}
+}
+
+// AssertNoSpan fails t if s has received a span named name.
+func (s *Server) AssertNoSpan(t testing.TB, name string) {
+	t.Helper()
+	for _, span := range s.Spans() {
+		if span.Name == name {
+			t.Errorf("unexpected span %q", name)
+			return
+		}
+	}
+}
+
+// AssertSpanCount fails t if s has not received n spans.
+func (s *Server) AssertSpanCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(s.Spans()); got != n {
+		t.Errorf("expected %d spans, got %d", n, got)
+	}
+}
+
+// AssertMetric fails t if s has not received a metric named name with the
+// attrs, and returns the first such metric.
+func (s *Server) AssertMetric(t testing.TB, name string, attrs map[string]interface{}) Metric {
+	t.Helper()
+	metrics := s.Metrics()
+	for _, m := range metrics {
+		if m.Name == name && hasAttributes(m.Attributes, attrs) {
+			return m
+		}
+	}
+	t.Errorf("no metric %q with attributes %v in %d metrics", name, attrs, len(metrics))
+	return Metric{}
+}
+
+// AssertMetricValue fails t if the count and gauge metrics named name with
+// the attrs received by s do not add up to value.
+func (s *Server) AssertMetricValue(t testing.TB, name string, attrs map[string]interface{}, value float64) {
+	t.Helper()
+	var sum float64
+	var found bool
+	for _, m := range s.Metrics() {
+		if m.Name == name && hasAttributes(m.Attributes, attrs) {
+			sum += m.Value
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no metric %q with attributes %v", name, attrs)
+	} else if sum != value {
+		t.Errorf("metric %q with attributes %v: got %v, want %v", name, attrs, sum, value)
+	}
+}
+
+// AssertEvent fails t if s has not received an event of eventType with the
+// attrs, and returns the first such event.
+func (s *Server) AssertEvent(t testing.TB, eventType string, attrs map[string]interface{}) Event {
+	t.Helper()
+	events := s.Events()
+	for _, e := range events {
+		if e.Type == eventType && hasAttributes(e.Attributes, attrs) {
+			return e
+		}
+	}
+	t.Errorf("no %s event with attributes %v in %d events", eventType, attrs, len(events))
+	return Event{}
+}
+
+// AssertLog fails t if s has not received a log with message and the attrs,
+// and returns the first such log.
+func (s *Server) AssertLog(t testing.TB, message string, attrs map[string]interface{}) Log {
+	t.Helper()
+	logs := s.Logs()
+	for _, l := range logs {
+		if l.Message == message && hasAttributes(l.Attributes, attrs) {
+			return l
+		}
+	}
+	t.Errorf("no log %q with attributes %v in %d logs", message, attrs, len(logs))
+	return Log{}
+}
+
+// hasAttributes returns if got has all of the attributes of want. Numbers
+// are compared as the float64 they are decoded to from JSON.
+func hasAttributes(got, want map[string]interface{}) bool {
+	for k, w := range want {
+		g, ok := got[k]
+		if !ok || !reflect.DeepEqual(g, jsonValue(w)) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonValue returns v as it is decoded from JSON.
+func jsonValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	}
+	return v
+}
@@ -0,0 +1,333 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Span is a span received by the Server. Its Attributes include the
+// attributes of the common block of its payload, those of the span take
+// precedence.
+type Span struct {
+	ID          string
+	TraceID     string
+	Timestamp   time.Time
+	Name        string
+	ParentID    string
+	ServiceName string
+	Duration    time.Duration
+	Attributes  map[string]interface{}
+	Events      []SpanEvent
+}
+
+// SpanEvent is an event of a Span.
+type SpanEvent struct {
+	Name       string
+	Timestamp  time.Time
+	Attributes map[string]interface{}
+}
+
+// Metric is a metric received by the Server. Its Attributes include the
+// attributes of the common block of its payload, those of the metric take
+// precedence.
+type Metric struct {
+	Name string
+	// Type is "count", "gauge", or "summary".
+	Type string
+	// Value is the value of count and gauge metrics.
+	Value float64
+	// Summary is the value of summary metrics.
+	Summary    Summary
+	Timestamp  time.Time
+	Interval   time.Duration
+	Attributes map[string]interface{}
+}
+
+// Summary is the value of a summary Metric.
+type Summary struct {
+	Count float64
+	Sum   float64
+	Min   float64
+	Max   float64
+}
+
+// Event is a custom event received by the Server.
+type Event struct {
+	Type      string
+	Timestamp time.Time
+	// Attributes are the attributes of the event other than its type and
+	// timestamp.
+	Attributes map[string]interface{}
+}
+
+// Log is a log received by the Server. Its Attributes include the attributes
+// of the common block of its payload, those of the log take precedence.
+type Log struct {
+	Message    string
+	Timestamp  time.Time
+	Attributes map[string]interface{}
+}
+
+// commonBlock is the common block of a trace, metric, or log payload.
+type commonBlock struct {
+	Timestamp  *int64                 `json:"timestamp"`
+	Interval   *int64                 `json:"interval.ms"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+type spanJSON struct {
+	ID         string                 `json:"id"`
+	TraceID    string                 `json:"trace.id"`
+	Timestamp  int64                  `json:"timestamp"`
+	Attributes map[string]interface{} `json:"attributes"`
+	Events     []struct {
+		Name       string                 `json:"name"`
+		Timestamp  int64                  `json:"timestamp"`
+		Attributes map[string]interface{} `json:"attributes"`
+	} `json:"events"`
+}
+
+type metricJSON struct {
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Value      json.RawMessage        `json:"value"`
+	Timestamp  *int64                 `json:"timestamp"`
+	Interval   *int64                 `json:"interval.ms"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+type logJSON struct {
+	Message    string                 `json:"message"`
+	Timestamp  int64                  `json:"timestamp"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// millis returns the time of a timestamp in milliseconds since the epoch.
+func millis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// merge returns the attributes of common overridden by attrs.
+func merge(common, attrs map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(common)+len(attrs))
+	for k, v := range common {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	return merged
+}
+
+// validateAttributes returns an error if an attribute value is not a string,
+// number, or boolean.
+func validateAttributes(attrs map[string]interface{}) error {
+	for k, v := range attrs {
+		if k == "" {
+			return errors.New("empty attribute name")
+		}
+		switch v.(type) {
+		case string, float64, bool:
+		default:
+			return fmt.Errorf("attribute %q: invalid value %v", k, v)
+		}
+	}
+	return nil
+}
+
+// parseSpans parses and validates a Trace API payload.
+func parseSpans(body []byte) ([]Span, error) {
+	var batches []struct {
+		Common commonBlock `json:"common"`
+		Spans  []spanJSON  `json:"spans"`
+	}
+	if err := json.Unmarshal(body, &batches); err != nil {
+		return nil, err
+	}
+	var spans []Span
+	for _, b := range batches {
+		if err := validateAttributes(b.Common.Attributes); err != nil {
+			return nil, fmt.Errorf("common block: %w", err)
+		}
+		for _, s := range b.Spans {
+			if s.ID == "" {
+				return nil, errors.New("span without id")
+			}
+			if s.TraceID == "" {
+				return nil, fmt.Errorf("span %s: no trace.id", s.ID)
+			}
+			if s.Timestamp <= 0 {
+				return nil, fmt.Errorf("span %s: invalid timestamp %d", s.ID, s.Timestamp)
+			}
+			if err := validateAttributes(s.Attributes); err != nil {
+				return nil, fmt.Errorf("span %s: %w", s.ID, err)
+			}
+			span := Span{
+				ID:         s.ID,
+				TraceID:    s.TraceID,
+				Timestamp:  millis(s.Timestamp),
+				Attributes: merge(b.Common.Attributes, s.Attributes),
+			}
+			span.Name, _ = span.Attributes["name"].(string)
+			span.ParentID, _ = span.Attributes["parent.id"].(string)
+			span.ServiceName, _ = span.Attributes["service.name"].(string)
+			if ms, ok := span.Attributes["duration.ms"].(float64); ok {
+				if ms < 0 {
+					return nil, fmt.Errorf("span %s: negative duration %vms", s.ID, ms)
+				}
+				span.Duration = time.Duration(ms * float64(time.Millisecond))
+			}
+			for _, e := range s.Events {
+				if err := validateAttributes(e.Attributes); err != nil {
+					return nil, fmt.Errorf("span %s event %s: %w", s.ID, e.Name, err)
+				}
+				span.Events = append(span.Events, SpanEvent{
+					Name:       e.Name,
+					Timestamp:  millis(e.Timestamp),
+					Attributes: e.Attributes,
+				})
+			}
+			spans = append(spans, span)
+		}
+	}
+	return spans, nil
+}
+
+// parseMetrics parses and validates a Metric API payload.
+func parseMetrics(body []byte) ([]Metric, error) {
+	var batches []struct {
+		Common  commonBlock  `json:"common"`
+		Metrics []metricJSON `json:"metrics"`
+	}
+	if err := json.Unmarshal(body, &batches); err != nil {
+		return nil, err
+	}
+	var metrics []Metric
+	for _, b := range batches {
+		if err := validateAttributes(b.Common.Attributes); err != nil {
+			return nil, fmt.Errorf("common block: %w", err)
+		}
+		for _, m := range b.Metrics {
+			if m.Name == "" {
+				return nil, errors.New("metric without name")
+			}
+			if err := validateAttributes(m.Attributes); err != nil {
+				return nil, fmt.Errorf("metric %s: %w", m.Name, err)
+			}
+			metric := Metric{
+				Name:       m.Name,
+				Type:       m.Type,
+				Attributes: merge(b.Common.Attributes, m.Attributes),
+			}
+			if ts := m.Timestamp; ts != nil {
+				metric.Timestamp = millis(*ts)
+			} else if ts := b.Common.Timestamp; ts != nil {
+				metric.Timestamp = millis(*ts)
+			}
+			if iv := m.Interval; iv != nil {
+				metric.Interval = time.Duration(*iv) * time.Millisecond
+			} else if iv := b.Common.Interval; iv != nil {
+				metric.Interval = time.Duration(*iv) * time.Millisecond
+			}
+			if metric.Interval < 0 {
+				return nil, fmt.Errorf("metric %s: negative interval.ms", m.Name)
+			}
+
+			switch m.Type {
+			case "gauge", "count":
+				if err := json.Unmarshal(m.Value, &metric.Value); err != nil {
+					return nil, fmt.Errorf("metric %s: invalid value: %w", m.Name, err)
+				}
+			case "summary":
+				var s struct {
+					Count *float64 `json:"count"`
+					Sum   *float64 `json:"sum"`
+					Min   *float64 `json:"min"`
+					Max   *float64 `json:"max"`
+				}
+				if err := json.Unmarshal(m.Value, &s); err != nil {
+					return nil, fmt.Errorf("metric %s: invalid value: %w", m.Name, err)
+				}
+				if s.Count == nil || s.Sum == nil {
+					return nil, fmt.Errorf("metric %s: summary without count or sum", m.Name)
+				}
+				metric.Summary = Summary{Count: *s.Count, Sum: *s.Sum}
+				if s.Min != nil {
+					metric.Summary.Min = *s.Min
+				}
+				if s.Max != nil {
+					metric.Summary.Max = *s.Max
+				}
+			default:
+				return nil, fmt.Errorf("metric %s: invalid type %q", m.Name, m.Type)
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics, nil
+}
+
+// parseEvents parses and validates an Event API payload.
+func parseEvents(body []byte) ([]Event, error) {
+	var raw []map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(raw))
+	for _, attrs := range raw {
+		eventType, _ := attrs["eventType"].(string)
+		if eventType == "" {
+			return nil, errors.New("event without eventType")
+		}
+		if err := validateAttributes(attrs); err != nil {
+			return nil, fmt.Errorf("event %s: %w", eventType, err)
+		}
+		event := Event{Type: eventType, Attributes: make(map[string]interface{}, len(attrs))}
+		for k, v := range attrs {
+			switch k {
+			case "eventType":
+			case "timestamp":
+				if ms, ok := v.(float64); ok {
+					event.Timestamp = millis(int64(ms))
+				}
+			default:
+				event.Attributes[k] = v
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// parseLogs parses and validates a Log API payload.
+func parseLogs(body []byte) ([]Log, error) {
+	var batches []struct {
+		Common commonBlock `json:"common"`
+		Logs   []logJSON   `json:"logs"`
+	}
+	if err := json.Unmarshal(body, &batches); err != nil {
+		return nil, err
+	}
+	var logs []Log
+	for _, b := range batches {
+		if err := validateAttributes(b.Common.Attributes); err != nil {
+			return nil, fmt.Errorf("common block: %w", err)
+		}
+		for _, l := range b.Logs {
+			if err := validateAttributes(l.Attributes); err != nil {
+				return nil, fmt.Errorf("log %q: %w", l.Message, err)
+			}
+			logs = append(logs, Log{
+				Message:    l.Message,
+				Timestamp:  millis(l.Timestamp),
+				Attributes: merge(b.Common.Attributes, l.Attributes),
+			})
+		}
+	}
+	return logs, nil
+}
@@ -0,0 +1,304 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package newrelictest provides a fake New Relic ingest server for testing
+// code instrumented with the New Relic OpenTelemetry exporter.
+//
+// The Server accepts the payloads of the Trace, Metric, Event, and Log APIs,
+// validates them the way New Relic does, and keeps the data they contain so
+// tests can make assertions about it:
+//
+//	srv := newrelictest.NewServer()
+//	defer srv.Close()
+//
+//	exporter, err := srv.NewExporter("my-service")
+//	// ... export spans and metrics with the exporter ...
+//	exporter.Shutdown(ctx)
+//
+//	srv.AssertNoErrors(t)
+//	srv.AssertSpan(t, "GET /users", map[string]interface{}{"http.status_code": 200})
+//
+// The Server can also simulate ingest failures with Respond and SetLatency.
+package newrelictest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic"
+)
+
+// APIKey is the API key used by exporters created with Server.NewExporter.
+const APIKey = "newrelictest-api-key"
+
+// Paths of the ingest APIs served by the Server.
+const (
+	SpansPath   = "/trace/v1"
+	MetricsPath = "/metric/v1"
+	EventsPath  = "/v1/accounts/events"
+	LogsPath    = "/log/v1"
+)
+
+// Response is a response the Server sends instead of accepting a payload.
+type Response struct {
+	// StatusCode is the status code of the response, 202 Accepted if
+	// zero.
+	StatusCode int
+	// RetryAfter is sent in the Retry-After header if positive.
+	RetryAfter time.Duration
+	// Delay is how long the Server waits before responding.
+	Delay time.Duration
+}
+
+// Request is a request received by the Server.
+type Request struct {
+	Path   string
+	Header http.Header
+	// Body is the uncompressed request body.
+	Body []byte
+	// StatusCode is the status code the Server responded with.
+	StatusCode int
+	// Err is why the payload was rejected, if it was invalid.
+	Err error
+}
+
+// Server is a fake New Relic ingest server.
+type Server struct {
+	*httptest.Server
+
+	lock      sync.Mutex
+	responses []Response
+	latency   time.Duration
+	requests  []Request
+	spans     []Span
+	metrics   []Metric
+	events    []Event
+	logs      []Log
+}
+
+// NewServer starts and returns a new Server. The caller should call Close
+// when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Config returns the telemetry.Config options that send the data of a
+// harvester to s.
+func (s *Server) Config() []func(*telemetry.Config) {
+	return []func(*telemetry.Config){
+		telemetry.ConfigSpansURLOverride(s.URL + SpansPath),
+		telemetry.ConfigMetricsURLOverride(s.URL + MetricsPath),
+		telemetry.ConfigEventsURLOverride(s.URL + EventsPath),
+		telemetry.ConfigLogsURLOverride(s.URL + LogsPath),
+		func(cfg *telemetry.Config) { cfg.Client.Transport = s.Client().Transport },
+	}
+}
+
+// NewExporter returns an Exporter that sends its data to s when it is shut
+// down. The options are applied after those configuring the Exporter for s.
+func (s *Server) NewExporter(service string, options ...newrelic.Option) (*newrelic.Exporter, error) {
+	config := append(s.Config(), telemetry.ConfigHarvestPeriod(0))
+	return newrelic.NewExporterWithOptions(service, APIKey,
+		append([]newrelic.Option{newrelic.WithTelemetryConfig(config...)}, options...)...)
+}
+
+// Respond queues responses sent to the next requests instead of accepting
+// their payloads.
+func (s *Server) Respond(responses ...Response) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// Fail responds to the next n requests with statusCode, e.g. 429 or 503.
+func (s *Server) Fail(n, statusCode int) {
+	for i := 0; i < n; i++ {
+		s.Respond(Response{StatusCode: statusCode})
+	}
+}
+
+// SetLatency delays all responses by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.latency = d
+}
+
+// Reset discards the requests and data received by s and the queued
+// responses.
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.responses = nil
+	s.requests = nil
+	s.spans = nil
+	s.metrics = nil
+	s.events = nil
+	s.logs = nil
+}
+
+// Requests returns the requests received by s.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Errors returns why the invalid payloads received by s were rejected.
+func (s *Server) Errors() []error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var errs []error
+	for _, r := range s.requests {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errs
+}
+
+// Spans returns the spans accepted by s.
+func (s *Server) Spans() []Span {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Span(nil), s.spans...)
+}
+
+// Metrics returns the metrics accepted by s.
+func (s *Server) Metrics() []Metric {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Metric(nil), s.metrics...)
+}
+
+// Events returns the custom events accepted by s.
+func (s *Server) Events() []Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// Logs returns the logs accepted by s.
+func (s *Server) Logs() []Log {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Log(nil), s.logs...)
+}
+
+// nextResponse returns the queued response to send, if any, and the latency.
+func (s *Server) nextResponse() (Response, bool, time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.responses) == 0 {
+		return Response{}, false, s.latency
+	}
+	r := s.responses[0]
+	s.responses = s.responses[1:]
+	return r, true, s.latency
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	req := Request{Path: r.URL.Path, Header: r.Header.Clone()}
+	body, err := readBody(r)
+	req.Body = body
+
+	resp, queued, latency := s.nextResponse()
+	time.Sleep(latency + resp.Delay)
+	switch {
+	case queued:
+		req.StatusCode = resp.StatusCode
+		if req.StatusCode == 0 {
+			req.StatusCode = http.StatusAccepted
+		}
+		if resp.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(resp.RetryAfter.Seconds())))
+		}
+	case err != nil:
+		req.StatusCode, req.Err = http.StatusBadRequest, err
+	case r.Method != http.MethodPost:
+		req.StatusCode, req.Err = http.StatusMethodNotAllowed, fmt.Errorf("invalid method %s", r.Method)
+	case r.Header.Get("Api-Key") == "" && r.Header.Get("X-License-Key") == "":
+		req.StatusCode, req.Err = http.StatusForbidden, fmt.Errorf("no API key")
+	default:
+		req.StatusCode, req.Err = s.accept(r.URL.Path, body)
+	}
+
+	s.lock.Lock()
+	s.requests = append(s.requests, req)
+	s.lock.Unlock()
+
+	if req.Err != nil {
+		http.Error(w, req.Err.Error(), req.StatusCode)
+		return
+	}
+	w.WriteHeader(req.StatusCode)
+}
+
+// readBody returns the uncompressed body of r.
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		return body, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip body: %w", err)
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
+
+// accept parses and keeps the data of the payload body sent to path and
+// returns the status code of the response.
+func (s *Server) accept(path string, body []byte) (int, error) {
+	switch path {
+	case SpansPath:
+		spans, err := parseSpans(body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		s.lock.Lock()
+		s.spans = append(s.spans, spans...)
+		s.lock.Unlock()
+	case MetricsPath:
+		metrics, err := parseMetrics(body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		s.lock.Lock()
+		s.metrics = append(s.metrics, metrics...)
+		s.lock.Unlock()
+	case EventsPath:
+		events, err := parseEvents(body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		s.lock.Lock()
+		s.events = append(s.events, events...)
+		s.lock.Unlock()
+	case LogsPath:
+		logs, err := parseLogs(body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		s.lock.Lock()
+		s.logs = append(s.logs, logs...)
+		s.lock.Unlock()
+	default:
+		return http.StatusNotFound, fmt.Errorf("unknown path %s", path)
+	}
+	return http.StatusAccepted, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	selector "go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic"
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/newrelictest"
+)
+
+func TestServer(t *testing.T) {
+	srv := newrelictest.NewServer()
+	defer srv.Close()
+
+	res := resource.NewWithAttributes(semconv.ServiceNameKey.String("service"))
+	e, err := srv.NewExporter("", newrelic.WithResource(res))
+	if err != nil {
+		t.Fatalf("failed to instantiate exporter: %v", err)
+	}
+	ctx := context.Background()
+
+	tp := trace.NewTracerProvider(trace.WithSyncer(e), trace.WithResource(res))
+	ctx, parent := tp.Tracer("test").Start(ctx, "parent")
+	_, child := tp.Tracer("test").Start(ctx, "child")
+	child.SetAttributes(attribute.Int("n", 1))
+	child.End()
+	parent.End()
+
+	control := controller.New(
+		processor.New(selector.NewWithInexpensiveDistribution(), e),
+		controller.WithExporter(e),
+		controller.WithResource(res),
+		controller.WithCollectPeriod(time.Hour),
+	)
+	if err := control.Start(ctx); err != nil {
+		t.Fatalf("starting controller: %v", err)
+	}
+	counter := metric.Must(control.MeterProvider().Meter("test")).NewInt64Counter("requests")
+	counter.Add(ctx, 2, attribute.String("route", "/a"))
+	counter.Add(ctx, 3, attribute.String("route", "/a"))
+	if err := control.Stop(ctx); err != nil {
+		t.Fatalf("stopping controller: %v", err)
+	}
+
+	if err := e.RecordEvent(ctx, "Purchase", attribute.Int("amount", 5)); err != nil {
+		t.Fatalf("recording event: %v", err)
+	}
+	if err := e.RecordLog(ctx, newrelic.LogRecord{Message: "hello"}); err != nil {
+		t.Fatalf("recording log: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	srv.AssertNoErrors(t)
+	srv.AssertSpanCount(t, 2)
+	p := srv.AssertSpan(t, "parent", map[string]interface{}{"service.name": "service"})
+	c := srv.AssertSpan(t, "child", map[string]interface{}{"n": 1})
+	if c.ParentID != p.ID || c.TraceID != p.TraceID {
+		t.Errorf("child span not linked to parent: %+v", c)
+	}
+	srv.AssertNoSpan(t, "other")
+	srv.AssertMetricValue(t, "requests", map[string]interface{}{"route": "/a", "service.name": "service"}, 5)
+	srv.AssertEvent(t, "Purchase", map[string]interface{}{"amount": 5})
+	srv.AssertLog(t, "hello", nil)
+
+	for _, r := range srv.Requests() {
+		if got := r.Header.Get("Api-Key"); got != newrelictest.APIKey {
+			t.Errorf("%s: Api-Key: got %q, want %q", r.Path, got, newrelictest.APIKey)
+		}
+	}
+}
+
+func TestServerFailures(t *testing.T) {
+	srv := newrelictest.NewServer()
+	defer srv.Close()
+
+	srv.Respond(newrelictest.Response{StatusCode: http.StatusTooManyRequests, Delay: 10 * time.Millisecond})
+	srv.Fail(1, http.StatusServiceUnavailable)
+	srv.SetLatency(time.Millisecond)
+
+	e, err := srv.NewExporter("service")
+	if err != nil {
+		t.Fatalf("failed to instantiate exporter: %v", err)
+	}
+	ctx := context.Background()
+	tp := trace.NewTracerProvider(trace.WithSyncer(e))
+	_, span := tp.Tracer("test").Start(ctx, "span")
+	span.End()
+	start := time.Now()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("response not delayed: %v", elapsed)
+	}
+
+	// The harvester retries after both failures.
+	var codes []int
+	for _, r := range srv.Requests() {
+		codes = append(codes, r.StatusCode)
+	}
+	if len(codes) != 3 || codes[0] != 429 || codes[1] != 503 || codes[2] != 202 {
+		t.Errorf("status codes: got %v, want [429 503 202]", codes)
+	}
+	srv.AssertSpan(t, "span", nil)
+}
+
+func TestServerDelayedResponse(t *testing.T) {
+	srv := newrelictest.NewServer()
+	defer srv.Close()
+
+	// A response without a status code is accepted.
+	srv.Respond(newrelictest.Response{Delay: time.Millisecond})
+
+	e, err := srv.NewExporter("service")
+	if err != nil {
+		t.Fatalf("failed to instantiate exporter: %v", err)
+	}
+	ctx := context.Background()
+	tp := trace.NewTracerProvider(trace.WithSyncer(e))
+	_, span := tp.Tracer("test").Start(ctx, "span")
+	span.End()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 1 || requests[0].StatusCode != http.StatusAccepted {
+		t.Errorf("expected 1 accepted request, got %v", requests)
+	}
+}
+
+func TestServerInvalidPayloads(t *testing.T) {
+	srv := newrelictest.NewServer()
+	defer srv.Close()
+
+	for _, test := range []struct {
+		path    string
+		payload string
+		apiKey  string
+		want    int
+	}{
+		{path: newrelictest.SpansPath, payload: `[{"spans":[{"id":"1","trace.id":"2","timestamp":1}]}]`, apiKey: "key", want: 202},
+		{path: newrelictest.SpansPath, payload: `[{"spans":[{"id":"1","trace.id":"2","timestamp":1}]}]`, want: 403},
+		{path: newrelictest.SpansPath, payload: `[{"spans":[{"trace.id":"2","timestamp":1}]}]`, apiKey: "key", want: 400},
+		{path: newrelictest.SpansPath, payload: `[{"spans":[{"id":"1","trace.id":"2","timestamp":1,"attributes":{"a":[1]}}]}]`, apiKey: "key", want: 400},
+		{path: newrelictest.SpansPath, payload: `[{"spans":[{"id":"1","trace.id":"2","timestamp":1,"attributes":{"duration.ms":-1}}]}]`, apiKey: "key", want: 400},
+		{path: newrelictest.MetricsPath, payload: `[{"metrics":[{"name":"m","type":"gauge","value":1}]}]`, apiKey: "key", want: 202},
+		{path: newrelictest.MetricsPath, payload: `[{"metrics":[{"name":"m","type":"count","value":1,"interval.ms":-1}]}]`, apiKey: "key", want: 400},
+		{path: newrelictest.MetricsPath, payload: `[{"common":{"interval.ms":10},"metrics":[{"name":"m","type":"count","value":1}]}]`, apiKey: "key", want: 202},
+		{path: newrelictest.MetricsPath, payload: `[{"metrics":[{"name":"m","type":"histogram","value":1}]}]`, apiKey: "key", want: 400},
+		{path: newrelictest.EventsPath, payload: `[{"name":"e"}]`, apiKey: "key", want: 400},
+		{path: newrelictest.LogsPath, payload: `not json`, apiKey: "key", want: 400},
+		{path: "/unknown", payload: `[]`, apiKey: "key", want: 404},
+	} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(test.payload))
+		gz.Close()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+test.path, &buf)
+		req.Header.Set("Content-Encoding", "gzip")
+		if test.apiKey != "" {
+			req.Header.Set("Api-Key", test.apiKey)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.want {
+			t.Errorf("%s %s: got status %d, want %d", test.path, test.payload, resp.StatusCode, test.want)
+		}
+	}
+	if got := len(srv.Errors()); got != 9 {
+		t.Errorf("expected 9 errors, got %d", got)
+	}
+	srv.AssertSpanCount(t, 1)
+
+	srv.Reset()
+	if got := len(srv.Requests()); got != 0 {
+		t.Errorf("expected no requests after reset, got %d", got)
+	}
+}
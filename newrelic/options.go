@@ -0,0 +1,380 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/transform"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc"
+)
+
+// Option configures an Exporter.
+type Option func(*config)
+
+// config contains the Exporter settings that are not part of the
+// telemetry.Config used by the underlying harvester.
+type config struct {
+	telemetryOptions []func(*telemetry.Config)
+
+	// selfMetrics reports exporter self-metrics when true.
+	selfMetrics bool
+
+	// spanMetricsNames is the maximum number of span names per service
+	// span metrics are derived for. Span metrics are disabled if zero.
+	spanMetricsNames int
+
+	// resource describes the entity producing the logs and events recorded
+	// by the Exporter.
+	resource *resource.Resource
+
+	// detectors build the Resource of pipelines created by
+	// NewExportPipeline. The default detectors are used unless
+	// detectorsSet is true.
+	detectors    []resource.Detector
+	detectorsSet bool
+
+	// semconvTarget is the semantic conventions version attributes are
+	// translated to. Attributes are not translated if empty.
+	semconvTarget string
+
+	// transactions maps entry spans to New Relic APM transactions when
+	// true, with the Apdex threshold apdexT.
+	transactions bool
+	apdexT       time.Duration
+
+	// traceObserverHost is the Infinite Tracing trace observer spans are
+	// streamed to instead of the Trace API, if set.
+	traceObserverHost        string
+	traceObserverQueueSize   int
+	traceObserverDialOptions []grpc.DialOption
+
+	// otlp sends spans and metrics to the New Relic OTLP endpoint when
+	// true, configured with otlpOptions.
+	otlp        bool
+	otlpOptions []otlphttp.Option
+
+	// destinations are sent the same data as the default destination.
+	destinations []Destination
+
+	// router chooses the destination of each item if not nil, keeping at
+	// most maxRoutedDestinations destinations, each evicted after being
+	// idle for routedIdleTimeout.
+	router                Router
+	maxRoutedDestinations int
+	routedIdleTimeout     time.Duration
+
+	// debugPath is where the payloads sent to New Relic are written, if
+	// set, configured with debugOptions.
+	debugPath    string
+	debugOptions []DebugSinkOption
+
+	// auditLogger records the requests sent to New Relic if not nil, with
+	// the auditSensitive attributes redacted.
+	auditLogger    AuditLogger
+	auditSensitive []string
+
+	// validation is how spans and metrics New Relic would not accept are
+	// handled. They are sent as they are if zero.
+	validation ValidationMode
+
+	// dropSkewedSpans drops spans that end before they start when true.
+	dropSkewedSpans bool
+
+	// metricNormalizer normalizes the names and units of metrics if not
+	// nil.
+	metricNormalizer *transform.MetricNormalizer
+}
+
+func newConfig(options ...Option) config {
+	var cfg config
+	for _, o := range options {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// WithTelemetryConfig passes options through to the telemetry.Config of the
+// harvester used to send data to New Relic.
+func WithTelemetryConfig(options ...func(*telemetry.Config)) Option {
+	return func(cfg *config) {
+		cfg.telemetryOptions = append(cfg.telemetryOptions, options...)
+	}
+}
+
+// WithSelfMetrics enables metrics that describe the exporter itself, such as
+// the number of span attributes, events, and links that were dropped by the
+// OpenTelemetry SDK before being exported.
+func WithSelfMetrics() Option {
+	return func(cfg *config) {
+		cfg.selfMetrics = true
+	}
+}
+
+// WithSpanMetrics enables request rate, error rate, and duration metrics
+// derived from server and consumer spans. The `otel.span.calls` count and
+// `otel.span.duration` summary, in milliseconds, are aggregated by service,
+// span name, span kind, and status code and sent every harvest.
+//
+// At most maxSpanNames distinct span names are tracked for each service, and
+// at most 100 services, each harvest to bound the cardinality of the metrics.
+// Spans with additional names or services are aggregated under the name
+// "other".
+func WithSpanMetrics(maxSpanNames int) Option {
+	return func(cfg *config) {
+		cfg.spanMetricsNames = maxSpanNames
+	}
+}
+
+// WithResourceDetectors sets the detectors NewExportPipeline and
+// InstallNewPipeline use to build the Resource of the pipeline. By default
+// the HostDetector, OSDetector, ProcessDetector, ContainerDetector, and
+// KubernetesDetector are used. Passing no detectors disables detection.
+//
+// The attributes of the `OTEL_RESOURCE_ATTRIBUTES` environment variable are
+// always merged into the Resource and take precedence over detected ones.
+func WithResourceDetectors(detectors ...resource.Detector) Option {
+	return func(cfg *config) {
+		cfg.detectors = detectors
+		cfg.detectorsSet = true
+	}
+}
+
+// WithResource sets the Resource describing the entity that produces the log
+// messages and custom events recorded with the Exporter. Spans and metrics are exported with
+// the Resource they were produced with.
+func WithResource(res *resource.Resource) Option {
+	return func(cfg *config) {
+		cfg.resource = res
+	}
+}
+
+// WithSemconvTarget translates the span attributes and metric labels known to
+// have been renamed by the OpenTelemetry semantic conventions, such as
+// `http.method` and `http.request.method`, to their names in the target
+// version, e.g. "1.21.0". This normalizes data from instrumentation using
+// different semantic conventions versions.
+func WithSemconvTarget(version string) Option {
+	return func(cfg *config) {
+		cfg.semconvTarget = version
+	}
+}
+
+// WithTransactions maps the entry spans of each service, root spans, spans
+// with a remote parent, and server and consumer spans, to New Relic APM
+// transactions so the service has the same APM summary pages as services
+// instrumented with New Relic agents.
+//
+// Entry spans are marked with the `nr.entryPoint`, `transaction.name`, and
+// `transaction.type` attributes. A `Transaction` event is recorded for each
+// and the `apm.service.transaction.duration` summary, in seconds, and the
+// `apm.service.apdex` count of web transactions by Apdex zone are
+// aggregated. Server spans with an HTTP method are web transactions.
+//
+// The Apdex threshold is apdexT, or 500 milliseconds if it is not positive.
+func WithTransactions(apdexT time.Duration) Option {
+	return func(cfg *config) {
+		cfg.transactions = true
+		cfg.apdexT = apdexT
+	}
+}
+
+// WithInfiniteTracing streams spans to the New Relic Infinite Tracing trace
+// observer at host, "host:port", over a long-lived gRPC stream instead of
+// sending them in batches to the Trace API. The trace observer host is shown
+// in the Infinite Tracing settings of your New Relic account, e.g.
+// "<id>.aws-us-east-1.tracing.edge.nr-data.net:443".
+//
+// Spans are queued, at most queueSize or 10000 if it is not positive, and
+// dropped with an error when the queue is full. The stream is reopened after
+// a backoff if it fails. The connection uses TLS, dialOptions are applied
+// after the defaults and can override them.
+func WithInfiniteTracing(host string, queueSize int, dialOptions ...grpc.DialOption) Option {
+	return func(cfg *config) {
+		cfg.traceObserverHost = host
+		cfg.traceObserverQueueSize = queueSize
+		cfg.traceObserverDialOptions = dialOptions
+	}
+}
+
+// WithOTLP sends spans and metrics as OTLP protocol buffers over HTTP to the
+// New Relic OTLP endpoint instead of the Trace and Metric APIs. The endpoint
+// is otlp.nr-data.net, or the endpoint of the region of the API key for keys
+// with a region prefix such as "eu01". The API key is sent in the `api-key`
+// header and payloads are gzip compressed. The options are applied after the
+// defaults and can override them, e.g. otlphttp.WithEndpoint.
+//
+// The service name and semantic conventions translation apply as for the New
+// Relic APIs, but WithValidation does not: the OTLP endpoint applies its own
+// limits. Logs, events, and the data derived from spans, such as span metrics
+// and transactions, are still sent to the New Relic APIs. Spans are streamed
+// to Infinite Tracing instead if WithInfiniteTracing is also used.
+//
+// The OTLP requests are not configured by WithTelemetryConfig: the HTTP
+// client and the Trace and Metric API URL overrides, such as those set by
+// NewExportPipeline from NEW_RELIC_TRACE_URL and NEW_RELIC_METRIC_URL, only
+// apply to the data still sent to the New Relic APIs. Use otlphttp options
+// such as otlphttp.WithEndpoint, otlphttp.WithTLSClientConfig, and
+// otlphttp.WithTimeout to configure the OTLP requests.
+func WithOTLP(options ...otlphttp.Option) Option {
+	return func(cfg *config) {
+		cfg.otlp = true
+		cfg.otlpOptions = options
+	}
+}
+
+// WithDestinations sends the data of the Exporter to each destination in
+// addition to the default destination of the API key passed to
+// NewExporterWithOptions, e.g. to send the same data to two accounts during a
+// migration. The data is transformed once and recorded with the harvesters
+// of every destination, which batch, retry, and send it independently so a
+// failing destination does not affect the others. Exporter.Health reports
+// the health of each destination.
+//
+// Data sent with OTLP or to Infinite Tracing is only sent to the default
+// destination.
+func WithDestinations(destinations ...Destination) Option {
+	return func(cfg *config) {
+		cfg.destinations = append(cfg.destinations, destinations...)
+	}
+}
+
+// WithRouter sends each span, metric, event, and log to the destination
+// chosen by router, e.g. the New Relic account of the tenant of a
+// multi-tenant service. Data the router does not choose a destination for is
+// sent to the default destinations.
+//
+// The harvesters of a destination are created the first time it is chosen.
+// At most maxDestinations, or 100 if it is not positive, destinations are
+// kept; the least recently used is evicted to make room for a new one.
+// Destinations not chosen for idleTimeout, or 10 minutes if it is not
+// positive, are evicted too. Evicted destinations are sent the data they
+// hold with the next harvest.
+//
+// Spans and metrics sent with OTLP or to Infinite Tracing cannot be routed,
+// NewExporterWithOptions returns an error if WithRouter is used with
+// WithOTLP or WithInfiniteTracing.
+func WithRouter(router Router, maxDestinations int, idleTimeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.router = router
+		cfg.maxRoutedDestinations = maxDestinations
+		cfg.routedIdleTimeout = idleTimeout
+	}
+}
+
+// WithDebugSink writes the uncompressed JSON payloads sent to New Relic, as
+// DebugPayload objects, to path: stdout if path is "-", a new file for each
+// payload if path is an existing directory, or else the file at path. It is
+// meant for debugging how data is mapped to New Relic and can be used with
+// DebugOnly to write the payloads instead of sending them.
+//
+// Payloads are written for every request, so a payload is written again
+// when a request is retried. Payloads that cannot be written are still sent,
+// unless DebugOnly is used, and the error is sent to the OpenTelemetry error
+// handler. Data sent with OTLP or to Infinite Tracing is not written.
+func WithDebugSink(path string, options ...DebugSinkOption) Option {
+	return func(cfg *config) {
+		cfg.debugPath = path
+		cfg.debugOptions = options
+	}
+}
+
+// WithAuditLog records each request sent to New Relic with logger: its
+// destination, endpoint, size, signal and number of items, response status
+// code, latency, and uncompressed body. The API key headers are redacted, as
+// are the values of the sensitive attributes at any depth of the body, e.g.
+// "user.email". Use NewAuditLogWriter to write the records as JSON lines.
+//
+// Every request is recorded, including retries. Data sent with OTLP or to
+// Infinite Tracing, and payloads only written to a debug sink with
+// DebugOnly, are not recorded.
+func WithAuditLog(logger AuditLogger, sensitive ...string) Option {
+	return func(cfg *config) {
+		cfg.auditLogger = logger
+		cfg.auditSensitive = sensitive
+	}
+}
+
+// WithValidation checks spans and metrics against the rules of the New Relic
+// ingest APIs before they are recorded: the format of IDs, the range of
+// timestamps, durations, and intervals, the types and limits of attributes,
+// and the validity of metric names and values. New Relic silently discards
+// data that breaks them.
+//
+// The mode selects whether invalid data is repaired or dropped. The number
+// of repairs and of dropped spans and metrics are reported as the
+// `newrelic.exporter.validation.repairs` and
+// `newrelic.exporter.validation.dropped` metrics.
+func WithValidation(mode ValidationMode) Option {
+	return func(cfg *config) {
+		cfg.validation = mode
+	}
+}
+
+// ClockSkewPolicy is how the Exporter handles spans that end before they
+// start, because the clock was adjusted while they were active or they were
+// not ended.
+type ClockSkewPolicy int
+
+const (
+	// ClockSkewRepair sends the spans with a zero duration, marked with the
+	// `nr.clock_skew` attribute. Transactions and span metrics are derived
+	// from the repaired spans.
+	ClockSkewRepair ClockSkewPolicy = iota
+	// ClockSkewDrop drops the spans.
+	ClockSkewDrop
+)
+
+// WithClockSkewPolicy sets how spans that end before they start are handled,
+// ClockSkewRepair by default. Either way they are reported to the
+// OpenTelemetry error handler.
+//
+// Spans with an all-zero trace or span ID are always dropped and reported to
+// the error handler, New Relic cannot accept them.
+func WithClockSkewPolicy(policy ClockSkewPolicy) Option {
+	return func(cfg *config) {
+		cfg.dropSkewedSpans = policy == ClockSkewDrop
+	}
+}
+
+// MetricNormalizationOption configures metric normalization.
+type MetricNormalizationOption func(*transform.MetricNormalizer)
+
+// MetricNamespace prefixes metric names with namespace and a '.', e.g.
+// "myapp.http.server.duration" for namespace "myapp".
+func MetricNamespace(namespace string) MetricNormalizationOption {
+	return func(n *transform.MetricNormalizer) {
+		n.Namespace = namespace
+	}
+}
+
+// MetricDurationsInSeconds converts the values of metrics with a duration
+// unit, such as "ms" or "us", to seconds so durations are reported
+// consistently.
+func MetricDurationsInSeconds() MetricNormalizationOption {
+	return func(n *transform.MetricNormalizer) {
+		n.Seconds = true
+	}
+}
+
+// WithMetricNormalization normalizes the names and units of the metrics of
+// instruments for New Relic. Names are sanitized to the characters New Relic
+// allows, letters, digits, '_', '.', and ':', other characters being replaced
+// with '_'. The UCUM units of instruments are converted to readable names in
+// the `unit` attribute, e.g. "milliseconds" for "ms", "bytes" for "By", and
+// "bytes/second" for "By/s". Dimensionless metrics, with unit "1", have no
+// `unit` attribute.
+//
+// Metrics sent with OTLP are not normalized.
+func WithMetricNormalization(options ...MetricNormalizationOption) Option {
+	return func(cfg *config) {
+		cfg.metricNormalizer = &transform.MetricNormalizer{}
+		for _, o := range options {
+			o(cfg.metricNormalizer)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+
+	exportmetric "go.opentelemetry.io/otel/sdk/export/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// New Relic OTLP endpoints.
+const (
+	otlpEndpointUS = "otlp.nr-data.net:4318"
+	// otlpEndpointRegion is the endpoint of the region of a license key
+	// with a region prefix.
+	otlpEndpointRegion = "otlp.%s.nr-data.net:4318"
+
+	// otlpAPIKeyHeader is the header the API key is sent with.
+	otlpAPIKeyHeader = "api-key"
+)
+
+// licenseKeyRegion matches the region prefix of a license key, e.g. "eu01" of
+// "eu01xx...". Keys of the US region have no prefix.
+var licenseKeyRegion = regexp.MustCompile(`^([a-z]{2}\d{2})x`)
+
+// otlpEndpoint returns the New Relic OTLP endpoint, "host:port", of the region
+// of apiKey.
+func otlpEndpoint(apiKey string) string {
+	if m := licenseKeyRegion.FindStringSubmatch(apiKey); m != nil {
+		return fmt.Sprintf(otlpEndpointRegion, m[1])
+	}
+	return otlpEndpointUS
+}
+
+// newOTLPExporter returns an OTLP exporter sending gzip compressed protocol
+// buffers over HTTP to the New Relic OTLP endpoint of the region of apiKey.
+// Metrics are sent with delta temporality like the Metric API expects.
+func newOTLPExporter(apiKey string, options ...otlphttp.Option) (*otlp.Exporter, error) {
+	driver := otlphttp.NewDriver(append([]otlphttp.Option{
+		otlphttp.WithEndpoint(otlpEndpoint(apiKey)),
+		otlphttp.WithHeaders(map[string]string{otlpAPIKeyHeader: apiKey}),
+		otlphttp.WithCompression(otlp.GzipCompression),
+	}, options...)...)
+	return otlp.NewExporter(
+		context.Background(),
+		driver,
+		otlp.WithMetricExportKindSelector(exportmetric.DeltaExportKindSelector()),
+	)
+}
+
+// otlpResource returns res with the service name of the Exporter if res does
+// not contain one.
+func (e *Exporter) otlpResource(res *resource.Resource) *resource.Resource {
+	if e.serviceName == "" {
+		return res
+	}
+	if _, ok := res.Set().Value(semconv.ServiceNameKey); ok {
+		return res
+	}
+	return resource.Merge(res, resource.NewWithAttributes(semconv.ServiceNameKey.String(e.serviceName)))
+}
+
+// otlpSpan returns span with the service name of the Exporter added to its
+// Resource if needed.
+func (e *Exporter) otlpSpan(span *sdktrace.SpanSnapshot) *sdktrace.SpanSnapshot {
+	res := e.otlpResource(span.Resource)
+	if res == span.Resource {
+		return span
+	}
+	// Modify a copy, the snapshot is shared with other span processors.
+	s := *span
+	s.Resource = res
+	return &s
+}
+
+// otlpRecord returns record with translated labels and the service name of
+// the Exporter added to its Resource if needed.
+func (e *Exporter) otlpRecord(record exportmetric.Record) exportmetric.Record {
+	labels := record.Labels()
+	if e.semconv != nil {
+		translated := attribute.NewSet(e.semconv.Translate(labels.ToSlice())...)
+		labels = &translated
+	}
+	return exportmetric.NewRecord(record.Descriptor(), labels, e.otlpResource(record.Resource()), record.Aggregation(), record.StartTime(), record.EndTime())
+}
+
+// otlpCheckpointSet is a CheckpointSet whose records are transformed before
+// they are exported with OTLP.
+type otlpCheckpointSet struct {
+	exportmetric.CheckpointSet
+	transform func(exportmetric.Record) exportmetric.Record
+}
+
+func (cps otlpCheckpointSet) ForEach(selector exportmetric.ExportKindSelector, f func(exportmetric.Record) error) error {
+	return cps.CheckpointSet.ForEach(selector, func(r exportmetric.Record) error {
+		return f(cps.transform(r))
+	})
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/number"
+	exportmetric "go.opentelemetry.io/otel/sdk/export/metric"
+	sumAgg "go.opentelemetry.io/otel/sdk/metric/aggregator/sum"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	metricsv1 "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func TestOTLPEndpoint(t *testing.T) {
+	for _, test := range []struct {
+		apiKey string
+		want   string
+	}{
+		{apiKey: "0123456789abcdef0123456789abcdef0123NRAL", want: "otlp.nr-data.net:4318"},
+		{apiKey: "eu01xx6789abcdef0123456789abcdef0123NRAL", want: "otlp.eu01.nr-data.net:4318"},
+		{apiKey: "gov01x6789abcdef0123456789abcdef0123NRAL", want: "otlp.nr-data.net:4318"},
+		{apiKey: "", want: "otlp.nr-data.net:4318"},
+	} {
+		if got := otlpEndpoint(test.apiKey); got != test.want {
+			t.Errorf("otlpEndpoint(%q): got %s, want %s", test.apiKey, got, test.want)
+		}
+	}
+}
+
+// otlpCollector is a fake New Relic OTLP endpoint.
+type otlpCollector struct {
+	lock    sync.Mutex
+	keys    []string
+	traces  []*tracev1.ExportTraceServiceRequest
+	metrics []*metricsv1.ExportMetricsServiceRequest
+}
+
+func (c *otlpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.keys = append(c.keys, r.Header.Get(otlpAPIKeyHeader))
+	switch r.URL.Path {
+	case "/v1/traces":
+		req := &tracev1.ExportTraceServiceRequest{}
+		err = proto.Unmarshal(b, req)
+		c.traces = append(c.traces, req)
+	case "/v1/metrics":
+		req := &metricsv1.ExportMetricsServiceRequest{}
+		err = proto.Unmarshal(b, req)
+		c.metrics = append(c.metrics, req)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// otlpAttribute returns the string value of the attribute with key.
+func otlpAttribute(attrs []*commonv1.KeyValue, key string) string {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestOTLP(t *testing.T) {
+	collector := &otlpCollector{}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt,
+		WithSemconvTarget("1.21.0"),
+		// Does not apply to OTLP, the span has no timestamps.
+		WithValidation(ValidationStrict),
+		WithOTLP(
+			otlphttp.WithEndpoint(strings.TrimPrefix(srv.URL, "http://")),
+			otlphttp.WithInsecure(),
+		),
+	)
+
+	attrs := []attribute.KeyValue{attribute.String("net.peer.name", "example.com")}
+	span := &trace.SpanSnapshot{
+		SpanContext: testSpanContext(),
+		Name:        "span",
+		Attributes:  attrs,
+		Resource:    resource.NewWithAttributes(semconv.HostNameKey.String("host")),
+	}
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, []*trace.SpanSnapshot{span}); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if got := len(e.destinations[0].harvesters); got != 0 {
+		t.Errorf("expected no resource harvesters for OTLP spans, got %d", got)
+	}
+
+	desc := metric.NewDescriptor("metric", metric.CounterInstrumentKind, number.Int64Kind)
+	agg := sumAgg.New(1)[0]
+	if err := agg.Update(ctx, number.NewInt64Number(1), &desc); err != nil {
+		t.Fatal(err)
+	}
+	labels := attribute.NewSet(attrs...)
+	now := time.Now()
+	cps := &checkpointSet{records: []exportmetric.Record{
+		exportmetric.NewRecord(&desc, &labels, resource.NewWithAttributes(semconv.ServiceNameKey.String("other")), &agg, now, now),
+	}}
+	if err := e.Export(ctx, cps); err != nil {
+		t.Fatalf("exporting metrics: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	if spans := mockt.Spans(); len(spans) != 0 {
+		t.Errorf("expected no spans sent to the Trace API, got %d", len(spans))
+	}
+	if metrics := mockt.Metrics(); len(metrics) != 0 {
+		t.Errorf("expected no metrics sent to the Metric API, got %d", len(metrics))
+	}
+
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	for _, key := range collector.keys {
+		if key != "apiKey" {
+			t.Errorf("API key header: got %q, want apiKey", key)
+		}
+	}
+
+	if len(collector.traces) != 1 || len(collector.traces[0].ResourceSpans) != 1 {
+		t.Fatalf("expected 1 trace request with 1 resource, got %v", collector.traces)
+	}
+	rs := collector.traces[0].ResourceSpans[0]
+	if got := otlpAttribute(rs.Resource.Attributes, "service.name"); got != "service" {
+		t.Errorf("span service.name: got %q, want service", got)
+	}
+	if got := otlpAttribute(rs.Resource.Attributes, "host.name"); got != "host" {
+		t.Errorf("span host.name: got %q, want host", got)
+	}
+	otlpSpan := rs.InstrumentationLibrarySpans[0].Spans[0]
+	if got := otlpAttribute(otlpSpan.Attributes, "server.address"); got != "example.com" {
+		t.Errorf("span server.address: got %q, want example.com", got)
+	}
+	if span.Resource.Len() != 1 || span.Attributes[0].Key != "net.peer.name" {
+		t.Errorf("exported span snapshot modified: %v", span)
+	}
+
+	if len(collector.metrics) != 1 || len(collector.metrics[0].ResourceMetrics) != 1 {
+		t.Fatalf("expected 1 metric request with 1 resource, got %v", collector.metrics)
+	}
+	rm := collector.metrics[0].ResourceMetrics[0]
+	if got := otlpAttribute(rm.Resource.Attributes, "service.name"); got != "other" {
+		t.Errorf("metric service.name: got %q, want other", got)
+	}
+	m := rm.InstrumentationLibraryMetrics[0].Metrics[0]
+	sum := m.GetIntSum()
+	if sum == nil || len(sum.DataPoints) != 1 {
+		t.Fatalf("expected a sum with 1 data point, got %v", m)
+	}
+	if got := sum.AggregationTemporality.String(); got != "AGGREGATION_TEMPORALITY_DELTA" {
+		t.Errorf("metric temporality: got %s, want delta", got)
+	}
+	for _, l := range sum.DataPoints[0].Labels {
+		if l.Key != "server.address" {
+			t.Errorf("metric label not translated: %s", l.Key)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newrelicHeader is the header New Relic agents propagate distributed trace
+// payloads with.
+const newrelicHeader = "newrelic"
+
+// Fields of the New Relic distributed trace payload and tracestate entry.
+const (
+	payloadVersionMajor = 0
+	payloadVersionMinor = 1
+	payloadTypeApp      = "App"
+	// tracestateVersion and tracestateParentApp are the version and the
+	// parent type of application tracestate entries.
+	tracestateVersion   = "0"
+	tracestateParentApp = "0"
+	// tracestateFields is the number of fields of a tracestate entry.
+	tracestateFields = 9
+)
+
+// payload is the JSON of the New Relic distributed trace payload.
+type payload struct {
+	Version [2]int      `json:"v"`
+	Data    payloadData `json:"d"`
+}
+
+type payloadData struct {
+	Type          string  `json:"ty"`
+	Account       string  `json:"ac"`
+	App           string  `json:"ap"`
+	TransactionID string  `json:"tx,omitempty"`
+	ID            string  `json:"id,omitempty"`
+	TraceID       string  `json:"tr"`
+	Priority      float32 `json:"pr"`
+	Sampled       bool    `json:"sa"`
+	Timestamp     int64   `json:"ti"`
+	TrustKey      string  `json:"tk,omitempty"`
+}
+
+// DistributedTracePropagator is a TextMapPropagator that propagates the
+// W3C `traceparent` and `tracestate` headers with a New Relic tracestate
+// entry, along with the `newrelic` distributed trace header used by New
+// Relic agents. It connects traces across services instrumented with New
+// Relic agents and OpenTelemetry:
+//
+//	otel.SetTextMapPropagator(newrelic.NewDistributedTracePropagator(account, app, trustKey))
+//
+// The W3C headers are preferred when extracting, the `newrelic` header is
+// only used if they are missing or invalid.
+type DistributedTracePropagator struct {
+	account  string
+	app      string
+	trustKey string
+}
+
+var _ propagation.TextMapPropagator = DistributedTracePropagator{}
+
+// NewDistributedTracePropagator returns a DistributedTracePropagator for the
+// New Relic account and application ID of this service. The trusted account
+// key is the account ID of the parent account of the account, if any. The
+// account ID is used if trustKey is empty.
+func NewDistributedTracePropagator(account, app, trustKey string) DistributedTracePropagator {
+	if trustKey == "" {
+		trustKey = account
+	}
+	return DistributedTracePropagator{
+		account:  account,
+		app:      app,
+		trustKey: trustKey,
+	}
+}
+
+// tracestateKey returns the key of the New Relic tracestate entry of the
+// trusted account key.
+func tracestateKey(trustKey string) attribute.Key {
+	return attribute.Key(trustKey + "@nr")
+}
+
+// Inject writes the W3C and New Relic distributed trace headers of the span
+// context in ctx to carrier.
+func (p DistributedTracePropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	// The priority of the trace is kept as it is propagated.
+	priority := tracePriority(sc.TraceID(), sc.IsSampled())
+	if pr, ok := parseTracestatePriority(sc.TraceState().Get(tracestateKey(p.trustKey)).AsString()); ok {
+		priority = pr
+	}
+	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
+	spanID := sc.SpanID().String()
+
+	entry := strings.Join([]string{
+		tracestateVersion,
+		tracestateParentApp,
+		p.account,
+		p.app,
+		spanID,
+		spanID,
+		formatSampled(sc.IsSampled()),
+		formatPriority(priority),
+		strconv.FormatInt(timestamp, 10),
+	}, "-")
+	if ts, err := sc.TraceState().Insert(tracestateKey(p.trustKey).String(entry)); err == nil {
+		sc = sc.WithTraceState(ts)
+	}
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(ctx, sc), carrier)
+
+	pl := payload{
+		Version: [2]int{payloadVersionMajor, payloadVersionMinor},
+		Data: payloadData{
+			Type:          payloadTypeApp,
+			Account:       p.account,
+			App:           p.app,
+			TransactionID: spanID,
+			ID:            spanID,
+			TraceID:       sc.TraceID().String(),
+			Priority:      priority,
+			Sampled:       sc.IsSampled(),
+			Timestamp:     timestamp,
+		},
+	}
+	if p.trustKey != p.account {
+		pl.Data.TrustKey = p.trustKey
+	}
+	js, err := json.Marshal(pl)
+	if err != nil {
+		return
+	}
+	carrier.Set(newrelicHeader, base64.StdEncoding.EncodeToString(js))
+}
+
+// Extract reads the W3C distributed trace headers, or the New Relic header if
+// they are not valid, from carrier into the remote span context of the
+// returned Context. The returned Context is ctx if neither are valid.
+func (p DistributedTracePropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	if w3c := (propagation.TraceContext{}).Extract(ctx, carrier); w3c != ctx {
+		return w3c
+	}
+	sc, err := extractPayload(carrier.Get(newrelicHeader))
+	if err != nil {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Fields returns the headers written by Inject.
+func (p DistributedTracePropagator) Fields() []string {
+	return append(propagation.TraceContext{}.Fields(), newrelicHeader)
+}
+
+// extractPayload returns the span context of the base64 encoded New Relic
+// distributed trace payload h. The payload is kept as the New Relic entry of
+// the tracestate.
+func extractPayload(h string) (trace.SpanContext, error) {
+	if h == "" {
+		return trace.SpanContext{}, fmt.Errorf("missing %s header", newrelicHeader)
+	}
+	js, err := base64.StdEncoding.DecodeString(h)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	var pl payload
+	if err := json.Unmarshal(js, &pl); err != nil {
+		return trace.SpanContext{}, err
+	}
+	if pl.Version[0] > payloadVersionMajor {
+		return trace.SpanContext{}, fmt.Errorf("unsupported payload version: %d", pl.Version[0])
+	}
+
+	// Older agents use 16 character trace IDs.
+	traceID, err := trace.TraceIDFromHex(fmt.Sprintf("%032s", pl.Data.TraceID))
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	id := pl.Data.ID
+	if id == "" {
+		id = pl.Data.TransactionID
+	}
+	spanID, err := trace.SpanIDFromHex(id)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+
+	cfg := trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Remote:  true,
+	}
+	if pl.Data.Sampled {
+		cfg.TraceFlags = trace.FlagsSampled
+	}
+	trustKey := pl.Data.TrustKey
+	if trustKey == "" {
+		trustKey = pl.Data.Account
+	}
+	entry := strings.Join([]string{
+		tracestateVersion,
+		tracestateParentApp,
+		pl.Data.Account,
+		pl.Data.App,
+		pl.Data.ID,
+		pl.Data.TransactionID,
+		formatSampled(pl.Data.Sampled),
+		formatPriority(pl.Data.Priority),
+		strconv.FormatInt(pl.Data.Timestamp, 10),
+	}, "-")
+	if ts, err := trace.TraceStateFromKeyValues(tracestateKey(trustKey).String(entry)); err == nil {
+		cfg.TraceState = ts
+	}
+	return trace.NewSpanContext(cfg), nil
+}
+
+// tracePriority returns the priority of a trace with traceID. It is derived
+// from the trace ID so every service computes the same priority for a trace,
+// in [0, 1) with at most six decimal places as New Relic agents use. Sampled
+// traces have their priority increased by one.
+func tracePriority(traceID trace.TraceID, sampled bool) float32 {
+	r := float64(binary.BigEndian.Uint64(traceID[8:16])>>11) / (1 << 53)
+	priority := float32(math.Trunc(r*1e6) / 1e6)
+	if sampled {
+		priority++
+	}
+	return priority
+}
+
+// parseTracestatePriority returns the priority of the New Relic tracestate
+// entry value, if valid.
+func parseTracestatePriority(value string) (float32, bool) {
+	fields := strings.Split(value, "-")
+	if len(fields) != tracestateFields {
+		return 0, false
+	}
+	priority, err := strconv.ParseFloat(fields[7], 32)
+	if err != nil {
+		return 0, false
+	}
+	return float32(priority), true
+}
+
+func formatPriority(priority float32) string {
+	return strconv.FormatFloat(float64(priority), 'f', -1, 32)
+}
+
+func formatSampled(sampled bool) string {
+	if sampled {
+		return "1"
+	}
+	return "0"
+}
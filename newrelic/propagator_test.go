@@ -0,0 +1,169 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDistributedTracePropagatorInject(t *testing.T) {
+	p := NewDistributedTracePropagator("1", "2", "33")
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+	carrier := propagation.HeaderCarrier(http.Header{})
+	p.Inject(trace.ContextWithSpanContext(context.Background(), sc), carrier)
+
+	if got, want := carrier.Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"; got != want {
+		t.Errorf("traceparent: got %q, want %q", got, want)
+	}
+	priority := formatPriority(tracePriority(traceID, true))
+	tracestate := carrier.Get("tracestate")
+	if want := "33@nr=0-0-1-2-00f067aa0ba902b7-00f067aa0ba902b7-1-" + priority + "-"; !strings.HasPrefix(tracestate, want) {
+		t.Errorf("tracestate: got %q, want prefix %q", tracestate, want)
+	}
+
+	js, err := base64.StdEncoding.DecodeString(carrier.Get("newrelic"))
+	if err != nil {
+		t.Fatalf("decoding newrelic header: %v", err)
+	}
+	var got payload
+	if err := json.Unmarshal(js, &got); err != nil {
+		t.Fatalf("unmarshaling newrelic header: %v", err)
+	}
+	want := payload{
+		Version: [2]int{0, 1},
+		Data: payloadData{
+			Type:          "App",
+			Account:       "1",
+			App:           "2",
+			TransactionID: "00f067aa0ba902b7",
+			ID:            "00f067aa0ba902b7",
+			TraceID:       "4bf92f3577b34da6a3ce929d0e0e4736",
+			Priority:      tracePriority(traceID, true),
+			Sampled:       true,
+			Timestamp:     got.Data.Timestamp,
+			TrustKey:      "33",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newrelic header: got %#v, want %#v", got, want)
+	}
+}
+
+func TestDistributedTracePropagatorInjectInvalid(t *testing.T) {
+	carrier := propagation.HeaderCarrier(http.Header{})
+	NewDistributedTracePropagator("1", "2", "").Inject(context.Background(), carrier)
+	if len(carrier) != 0 {
+		t.Errorf("headers injected without a span context: %v", carrier)
+	}
+}
+
+func TestDistributedTracePropagatorExtractNewRelic(t *testing.T) {
+	p := NewDistributedTracePropagator("1", "2", "")
+	for _, test := range []struct {
+		name    string
+		header  string
+		traceID string
+		sampled bool
+	}{
+		{
+			name:    "full trace ID",
+			header:  `{"v":[0,1],"d":{"ty":"App","ac":"1","ap":"3","tx":"27856f70d3d314b7","id":"0b29fd3e43b8b5b1","tr":"4bf92f3577b34da6a3ce929d0e0e4736","pr":1.234567,"sa":true,"ti":1482959525577}}`,
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			sampled: true,
+		},
+		{
+			name:    "short trace ID",
+			header:  `{"v":[0,1],"d":{"ty":"App","ac":"1","ap":"3","tx":"27856f70d3d314b7","id":"0b29fd3e43b8b5b1","tr":"a3ce929d0e0e4736","pr":0.5,"sa":false,"ti":1482959525577}}`,
+			traceID: "0000000000000000a3ce929d0e0e4736",
+		},
+	} {
+		carrier := propagation.HeaderCarrier(http.Header{})
+		carrier.Set("newrelic", base64.StdEncoding.EncodeToString([]byte(test.header)))
+		sc := trace.SpanContextFromContext(p.Extract(context.Background(), carrier))
+		if !sc.IsValid() || !sc.IsRemote() {
+			t.Errorf("%s: invalid span context extracted: %#v", test.name, sc)
+			continue
+		}
+		if got := sc.TraceID().String(); got != test.traceID {
+			t.Errorf("%s: trace ID: got %s, want %s", test.name, got, test.traceID)
+		}
+		if got := sc.SpanID().String(); got != "0b29fd3e43b8b5b1" {
+			t.Errorf("%s: span ID: got %s, want 0b29fd3e43b8b5b1", test.name, got)
+		}
+		if sc.IsSampled() != test.sampled {
+			t.Errorf("%s: sampled: got %t, want %t", test.name, sc.IsSampled(), test.sampled)
+		}
+	}
+
+	for _, header := range []string{
+		"",
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte("{")),
+		base64.StdEncoding.EncodeToString([]byte(`{"v":[1,0],"d":{"tr":"a3ce929d0e0e4736","id":"0b29fd3e43b8b5b1"}}`)),
+		base64.StdEncoding.EncodeToString([]byte(`{"v":[0,1],"d":{"tr":"zz","id":"0b29fd3e43b8b5b1"}}`)),
+	} {
+		carrier := propagation.HeaderCarrier(http.Header{})
+		carrier.Set("newrelic", header)
+		ctx := context.Background()
+		if got := p.Extract(ctx, carrier); got != ctx {
+			t.Errorf("%q: expected context unchanged", header)
+		}
+	}
+}
+
+func TestDistributedTracePropagatorRoundTrip(t *testing.T) {
+	// A New Relic agent calls a service using the propagator, which then
+	// calls another service. The priority of the trace is preserved.
+	upstream := propagation.HeaderCarrier(http.Header{})
+	upstream.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	upstream.Set("tracestate", "33@nr=0-0-1-3-00f067aa0ba902b7-27856f70d3d314b7-1-1.234567-1482959525577,other=value")
+	upstream.Set("newrelic", "ignored")
+
+	p := NewDistributedTracePropagator("1", "2", "33")
+	ctx := p.Extract(context.Background(), upstream)
+	parent := trace.SpanContextFromContext(ctx)
+	spanID, _ := trace.SpanIDFromHex("0b29fd3e43b8b5b1")
+	child := parent.WithSpanID(spanID).WithRemote(false)
+
+	downstream := propagation.HeaderCarrier(http.Header{})
+	p.Inject(trace.ContextWithSpanContext(ctx, child), downstream)
+
+	tracestate := downstream.Get("tracestate")
+	if want := "33@nr=0-0-1-2-0b29fd3e43b8b5b1-0b29fd3e43b8b5b1-1-1.234567-"; !strings.HasPrefix(tracestate, want) {
+		t.Errorf("tracestate: got %q, want prefix %q", tracestate, want)
+	}
+	if !strings.HasSuffix(tracestate, ",other=value") {
+		t.Errorf("tracestate: other vendor entry not preserved: %q", tracestate)
+	}
+	if got, want := downstream.Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-0b29fd3e43b8b5b1-01"; got != want {
+		t.Errorf("traceparent: got %q, want %q", got, want)
+	}
+}
+
+func TestTracePriority(t *testing.T) {
+	for _, id := range []trace.TraceID{{}, {0xff}, {15: 0xff}, {8: 0xff, 9: 0xff, 10: 0xff, 11: 0xff, 12: 0xff, 13: 0xff, 14: 0xff, 15: 0xff}} {
+		p := tracePriority(id, false)
+		if p < 0 || p >= 1 {
+			t.Errorf("%s: priority out of range: %v", id, p)
+		}
+		if sampled := tracePriority(id, true); sampled != p+1 {
+			t.Errorf("%s: sampled priority: got %v, want %v", id, sampled, p+1)
+		}
+	}
+}
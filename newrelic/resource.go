@@ -0,0 +1,93 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"time"
+
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/detect"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// resourceDetectionTimeout bounds the Resource detection of
+// NewExportPipeline.
+const resourceDetectionTimeout = 5 * time.Second
+
+// HostDetector returns a Resource detector for the host name.
+func HostDetector() resource.Detector { return resource.Host{} }
+
+// OSDetector returns a Resource detector for the operating system type.
+func OSDetector() resource.Detector { return sdkDetector(resource.WithOSType()) }
+
+// ProcessDetector returns a Resource detector for the process ID, executable
+// name and path, and Go runtime version.
+func ProcessDetector() resource.Detector {
+	return sdkDetector(
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessExecutablePath(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+	)
+}
+
+// ContainerDetector returns a Resource detector for the ID of the container
+// the process runs in, read from the /proc cgroup files.
+func ContainerDetector() resource.Detector { return detect.Container(detect.OSSystem()) }
+
+// KubernetesDetector returns a Resource detector for the Kubernetes pod,
+// namespace, and node the process runs in. These are read from the
+// `K8S_POD_NAME`, `K8S_POD_UID`, `K8S_NAMESPACE_NAME`, and `K8S_NODE_NAME`
+// environment variables which can be set with the Kubernetes downward API.
+func KubernetesDetector() resource.Detector { return detect.Kubernetes(detect.OSSystem()) }
+
+// defaultDetectors returns the detectors used by NewExportPipeline if none
+// are configured with WithResourceDetectors.
+func defaultDetectors() []resource.Detector {
+	return []resource.Detector{
+		HostDetector(),
+		OSDetector(),
+		ProcessDetector(),
+		ContainerDetector(),
+		KubernetesDetector(),
+	}
+}
+
+// sdkDetector returns a Resource detector for the OpenTelemetry SDK
+// detectors configured by opts.
+func sdkDetector(opts ...resource.Option) resource.Detector { return sdkDetectors(opts) }
+
+type sdkDetectors []resource.Option
+
+func (d sdkDetectors) Detect(ctx context.Context) (*resource.Resource, error) {
+	return resource.New(ctx, append([]resource.Option{resource.WithoutBuiltin()}, d...)...)
+}
+
+// pipelineResource returns the Resource used by NewExportPipeline. It merges,
+// in increasing order of precedence, the detected attributes, the service
+// name, and the percent-decoded attributes of the `OTEL_RESOURCE_ATTRIBUTES`
+// environment variable. Detection errors are sent to the OpenTelemetry error
+// handler.
+func pipelineResource(ctx context.Context, service string, cfg config) *resource.Resource {
+	detectors := cfg.detectors
+	if !cfg.detectorsSet {
+		detectors = defaultDetectors()
+	}
+
+	r, err := resource.Detect(ctx, detectors...)
+	if err != nil {
+		otel.Handle(err)
+	}
+	if service != "" {
+		r = resource.Merge(r, resource.NewWithAttributes(semconv.ServiceNameKey.String(service)))
+	}
+	env, err := detect.Environment().Detect(ctx)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return resource.Merge(r, env)
+}
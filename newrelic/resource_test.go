@@ -0,0 +1,75 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+type staticDetector []attribute.KeyValue
+
+func (d staticDetector) Detect(context.Context) (*resource.Resource, error) {
+	return resource.NewWithAttributes(d...), nil
+}
+
+func TestPipelineResource(t *testing.T) {
+	defer os.Unsetenv("OTEL_RESOURCE_ATTRIBUTES")
+	os.Setenv("OTEL_RESOURCE_ATTRIBUTES", "host.name=env-host")
+
+	cfg := newConfig(WithResourceDetectors(staticDetector{
+		semconv.HostNameKey.String("detected-host"),
+		semconv.ServiceNameKey.String("detected-service"),
+		semconv.OSTypeKey.String("linux"),
+	}))
+	r := pipelineResource(context.Background(), "service", cfg)
+
+	want := resource.NewWithAttributes(
+		semconv.HostNameKey.String("env-host"),
+		semconv.ServiceNameKey.String("service"),
+		semconv.OSTypeKey.String("linux"),
+	)
+	if !r.Equal(want) {
+		t.Errorf("pipeline resource: got %v, want %v", r, want)
+	}
+}
+
+func TestPipelineResourceNoDetectors(t *testing.T) {
+	os.Unsetenv("OTEL_RESOURCE_ATTRIBUTES")
+
+	r := pipelineResource(context.Background(), "", newConfig(WithResourceDetectors()))
+	if r.Len() != 0 {
+		t.Errorf("pipeline resource: got %v, want empty", r)
+	}
+}
+
+func TestDefaultDetectors(t *testing.T) {
+	r, err := resource.Detect(context.Background(), defaultDetectors()...)
+	if err != nil {
+		t.Fatalf("detecting resource: %v", err)
+	}
+	got := make(map[attribute.Key]bool)
+	for iter := r.Iter(); iter.Next(); {
+		got[iter.Label().Key] = true
+	}
+	for _, key := range []attribute.Key{
+		semconv.HostNameKey,
+		semconv.OSTypeKey,
+		semconv.ProcessPIDKey,
+		semconv.ProcessExecutableNameKey,
+		semconv.ProcessRuntimeVersionKey,
+	} {
+		if !got[key] {
+			t.Errorf("%s not detected in %v", key, r)
+		}
+	}
+	if got[semconv.ProcessCommandArgsKey] || got[semconv.ProcessOwnerKey] {
+		t.Errorf("unexpected process attributes in %v", r)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Routing defaults.
+const (
+	defaultMaxRoutedDestinations = 100
+	defaultRoutedIdleTimeout     = 10 * time.Minute
+)
+
+// Router returns the destination of data produced by res, with attrs the
+// span attributes, metric labels, or event and log attributes of the data.
+// The data is sent to the default destinations if ok is false.
+//
+// Destinations are identified by their name, a destination is created the
+// first time its name is returned. The Router is called concurrently.
+type Router func(res *resource.Resource, attrs []attribute.KeyValue) (d Destination, ok bool)
+
+// router sends data to the destinations chosen by a Router.
+//
+// Routed destinations are created when they are first used and evicted when
+// they have not been used for idleTimeout, or when maxDestinations is
+// reached to make room for a new one. Evicted destinations are sent the data
+// they hold with the next harvest of the Exporter.
+type router struct {
+	route           Router
+	maxDestinations int
+	idleTimeout     time.Duration
+
+	lock         sync.Mutex
+	destinations map[string]*routedDestination
+	// evicted are the destinations evicted since the last harvest.
+	evicted []*destination
+}
+
+// routedDestination is a destination chosen by a Router.
+type routedDestination struct {
+	*destination
+	lastUsed time.Time
+}
+
+func newRouter(route Router, maxDestinations int, idleTimeout time.Duration) *router {
+	if maxDestinations <= 0 {
+		maxDestinations = defaultMaxRoutedDestinations
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = defaultRoutedIdleTimeout
+	}
+	return &router{
+		route:           route,
+		maxDestinations: maxDestinations,
+		idleTimeout:     idleTimeout,
+		destinations:    make(map[string]*routedDestination),
+	}
+}
+
+// destination returns the routed destination of d, created with create if
+// it does not exist. The least recently used destination is evicted if the
+// maximum number of destinations is reached.
+func (r *router) destination(d Destination, now time.Time, create func(Destination) (*destination, error)) (*destination, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if rd, ok := r.destinations[d.Name]; ok {
+		rd.lastUsed = now
+		return rd.destination, nil
+	}
+	dest, err := create(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.destinations) >= r.maxDestinations {
+		var lru *routedDestination
+		for _, rd := range r.destinations {
+			if lru == nil || rd.lastUsed.Before(lru.lastUsed) {
+				lru = rd
+			}
+		}
+		r.evict(lru)
+	}
+	r.destinations[d.Name] = &routedDestination{destination: dest, lastUsed: now}
+	return dest, nil
+}
+
+// evict removes rd from the routed destinations. r.lock must be held.
+func (r *router) evict(rd *routedDestination) {
+	delete(r.destinations, rd.name)
+	r.evicted = append(r.evicted, rd.destination)
+}
+
+// harvestable evicts the destinations that are idle at now and returns the
+// destinations to harvest: the routed destinations and those evicted since
+// the last harvest.
+func (r *router) harvestable(now time.Time) []*destination {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, rd := range r.destinations {
+		if now.Sub(rd.lastUsed) > r.idleTimeout {
+			r.evict(rd)
+		}
+	}
+	destinations := make([]*destination, 0, len(r.destinations)+len(r.evicted))
+	for _, rd := range r.destinations {
+		destinations = append(destinations, rd.destination)
+	}
+	destinations = append(destinations, r.evicted...)
+	r.evicted = nil
+	return destinations
+}
+
+// health returns the health of the routed destinations.
+func (r *router) health() []DestinationHealth {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	health := make([]DestinationHealth, 0, len(r.destinations))
+	for _, rd := range r.destinations {
+		health = append(health, rd.health.get())
+	}
+	return health
+}
+
+// routedHarvesterFor returns the harvesters used to send data produced by res
+// with attrs: the harvester of the routed destination if the Router chooses
+// one, or those of the default destinations.
+func (e *Exporter) routedHarvesterFor(res *resource.Resource, attrs []attribute.KeyValue) (harvesters, error) {
+	if e.router == nil {
+		return e.harvesterFor(res)
+	}
+	d, ok := e.router.route(res, attrs)
+	if !ok {
+		return e.harvesterFor(res)
+	}
+	dest, err := e.router.destination(d, time.Now(), e.newRoutedDestination)
+	if err != nil {
+		return nil, err
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	h, err := e.destinationHarvester(dest, res, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return harvesters{h}, nil
+}
+
+// newRoutedDestination creates the destination of d.
+func (e *Exporter) newRoutedDestination(d Destination) (*destination, error) {
+	dest := e.newDestination(d.Name, e.harvesterOptions(d.APIKey, d.Options...))
+	h, err := e.newHarvester(dest, nil)
+	if err != nil {
+		return nil, err
+	}
+	dest.harvester = h
+	return dest, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/transform"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestRouter(t *testing.T) {
+	defaultTransport := &MockTransport{}
+	tenants := map[string]*MockTransport{
+		"a": {},
+		"b": {},
+	}
+	route := func(_ *resource.Resource, attrs []attribute.KeyValue) (Destination, bool) {
+		for _, kv := range attrs {
+			if kv.Key == "tenant" {
+				mockt := tenants[kv.Value.AsString()]
+				return Destination{
+					Name:   kv.Value.AsString(),
+					APIKey: kv.Value.AsString() + "Key",
+					Options: []func(*telemetry.Config){
+						func(cfg *telemetry.Config) { cfg.Client.Transport = mockt },
+					},
+				}, true
+			}
+		}
+		return Destination{}, false
+	}
+	e := newTestExporter(t, "service", defaultTransport, WithRouter(route, 0, 0))
+
+	spans := newTestSpans("a1", "a2", "b1", "default")
+	spans[0].Attributes = []attribute.KeyValue{attribute.String("tenant", "a")}
+	spans[1].Attributes = []attribute.KeyValue{attribute.String("tenant", "a")}
+	spans[2].Attributes = []attribute.KeyValue{attribute.String("tenant", "b")}
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("shutting down exporter: %v", err)
+	}
+
+	for name, want := range map[string]int{"a": 2, "b": 1} {
+		if got := len(tenants[name].Spans()); got != want {
+			t.Errorf("tenant %s: expected %d spans, got %d", name, want, got)
+		}
+	}
+	if got := len(defaultTransport.Spans()); got != 1 {
+		t.Errorf("default destination: expected 1 span, got %d", got)
+	}
+
+	var names []string
+	for _, h := range e.Health() {
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"a", "b", DefaultDestination}; len(names) != 3 || names[0] != want[0] || names[1] != want[1] || names[2] != want[2] {
+		t.Errorf("destination health: got %v, want %v", names, want)
+	}
+}
+
+// destinationNames returns the sorted names of destinations.
+func destinationNames(destinations []*destination) []string {
+	var names []string
+	for _, d := range destinations {
+		names = append(names, d.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRouterEviction(t *testing.T) {
+	r := newRouter(nil, 2, time.Minute)
+	create := func(d Destination) (*destination, error) {
+		return (&Exporter{}).newDestination(d.Name, nil), nil
+	}
+	now := time.Now()
+	for i, name := range []string{"a", "b", "a", "c"} {
+		if _, err := r.destination(Destination{Name: name}, now.Add(time.Duration(i)*time.Second), create); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// b was the least recently used destination when c was created.
+	if got := destinationNames(r.harvestable(now.Add(4 * time.Second))); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("harvestable destinations: got %v, want [a b c]", got)
+	}
+	if got := len(r.destinations); got != 2 {
+		t.Errorf("expected 2 destinations, got %d", got)
+	}
+	if _, ok := r.destinations["b"]; ok {
+		t.Error("least recently used destination not evicted")
+	}
+
+	// The evicted destination is only harvested once.
+	if got := destinationNames(r.harvestable(now.Add(5 * time.Second))); len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Errorf("harvestable destinations: got %v, want [a c]", got)
+	}
+
+	// a was last used at 2s and c at 3s.
+	if got := destinationNames(r.harvestable(now.Add(62*time.Second + time.Millisecond))); len(got) != 2 {
+		t.Errorf("harvestable destinations: got %v, want [a c]", got)
+	}
+	if _, ok := r.destinations["a"]; ok {
+		t.Error("idle destination not evicted")
+	}
+	if _, ok := r.destinations["c"]; !ok {
+		t.Error("active destination evicted")
+	}
+}
+
+func TestRouterEvictionWhileRecording(t *testing.T) {
+	tenants := []*MockTransport{{}, {}}
+	route := func(_ *resource.Resource, attrs []attribute.KeyValue) (Destination, bool) {
+		for _, kv := range attrs {
+			if kv.Key == "tenant" {
+				mockt := tenants[kv.Value.AsInt64()]
+				return Destination{
+					Name:   kv.Value.Emit(),
+					APIKey: "tenantKey",
+					Options: []func(*telemetry.Config){
+						func(cfg *telemetry.Config) { cfg.Client.Transport = mockt },
+					},
+				}, true
+			}
+		}
+		return Destination{}, false
+	}
+	// Every new tenant evicts the previous one.
+	e := newTestExporter(t, "service", &MockTransport{}, WithRouter(route, 1, 0))
+
+	ctx := context.Background()
+	tenant := func(i int) []attribute.KeyValue {
+		return []attribute.KeyValue{attribute.Int("tenant", i)}
+	}
+
+	// A harvester of tenant 0 is in use while tenant 1 evicts it.
+	e.recording.RLock()
+	h, err := e.routedHarvesterFor(nil, tenant(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.routedHarvesterFor(nil, tenant(1)); err != nil {
+		t.Fatal(err)
+	}
+	harvested := make(chan struct{})
+	go func() {
+		defer close(harvested)
+		e.harvestNow(ctx)
+	}()
+	select {
+	case <-harvested:
+		t.Fatal("harvest did not wait for the recording")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if err := h.RecordSpan(transform.Span("service", newTestSpans("span")[0])); err != nil {
+		t.Fatal(err)
+	}
+	e.recording.RUnlock()
+	<-harvested
+
+	if got := len(tenants[0].Spans()); got != 1 {
+		t.Errorf("evicted tenant: expected 1 span, got %d", got)
+	}
+}
+
+func TestRouterUnsupportedOptions(t *testing.T) {
+	route := func(*resource.Resource, []attribute.KeyValue) (Destination, bool) {
+		return Destination{}, false
+	}
+	for name, option := range map[string]Option{
+		"OTLP":             WithOTLP(),
+		"Infinite Tracing": WithInfiniteTracing("localhost:443", 0),
+	} {
+		_, err := NewExporterWithOptions("service", "apiKey", WithRouter(route, 0, 0), option)
+		if err == nil {
+			t.Errorf("%s: expected an error for a router", name)
+		}
+	}
+}
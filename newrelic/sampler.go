@@ -0,0 +1,143 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// priorityAttrKey is the span attribute the sampling priority of a trace is
+// recorded with, as New Relic agents do.
+const priorityAttrKey = attribute.Key("priority")
+
+// defaultSamplerPeriod is the sampling period used by New Relic agents.
+const defaultSamplerPeriod = time.Minute
+
+// AdaptiveSampler is a Sampler implementing the adaptive sampling of New
+// Relic agents. It samples about target root spans every period, regardless
+// of throughput, so services instrumented with OpenTelemetry sample at the
+// same rate as those instrumented with New Relic agents.
+//
+// Each trace is assigned a priority derived from its trace ID, the same
+// priority DistributedTracePropagator propagates. The first target traces of the
+// first period are sampled. In the following periods traces are sampled if
+// their priority is above a threshold computed from the number of traces
+// seen in the previous period. If more than target traces are sampled in a
+// period, an exponential backoff is applied.
+//
+// Spans with a parent follow the sampling decision of the parent.
+type AdaptiveSampler struct {
+	period time.Duration
+	target uint64
+
+	lock sync.Mutex
+	rand *rand.Rand
+	// priorityMin is the minimum priority of sampled traces in the current
+	// period.
+	priorityMin float32
+	numSampled  uint64
+	numSeen     uint64
+	periodEnd   time.Time
+}
+
+var _ sdktrace.Sampler = (*AdaptiveSampler)(nil)
+
+// NewAdaptiveSampler returns an AdaptiveSampler that samples about target
+// traces every period. New Relic agents sample 10 transactions every minute
+// by default. A period of one minute is used if period is not positive.
+func NewAdaptiveSampler(target int, period time.Duration) *AdaptiveSampler {
+	return newAdaptiveSampler(target, period, time.Now())
+}
+
+func newAdaptiveSampler(target int, period time.Duration, now time.Time) *AdaptiveSampler {
+	if target < 0 {
+		target = 0
+	}
+	if period <= 0 {
+		period = defaultSamplerPeriod
+	}
+	return &AdaptiveSampler{
+		period:    period,
+		target:    uint64(target),
+		rand:      rand.New(rand.NewSource(now.UnixNano())),
+		periodEnd: now.Add(period),
+	}
+}
+
+// ShouldSample samples root spans by their priority and the spans with a
+// parent by the decision of the parent.
+func (s *AdaptiveSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	if psc.IsValid() {
+		decision := sdktrace.Drop
+		if psc.IsSampled() {
+			decision = sdktrace.RecordAndSample
+		}
+		return sdktrace.SamplingResult{
+			Decision:   decision,
+			Tracestate: psc.TraceState(),
+		}
+	}
+
+	priority := tracePriority(p.TraceID, false)
+	if !s.computeSampled(priority, time.Now()) {
+		return sdktrace.SamplingResult{Decision: sdktrace.Drop}
+	}
+	// Sampled traces are prioritized over unsampled ones by New Relic.
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordAndSample,
+		Attributes: []attribute.KeyValue{priorityAttrKey.Float64(float64(tracePriority(p.TraceID, true)))},
+	}
+}
+
+// Description returns the description of the sampler.
+func (s *AdaptiveSampler) Description() string {
+	return fmt.Sprintf("AdaptiveSampler{target:%d,period:%s}", s.target, s.period)
+}
+
+// computeSampled returns true if a trace with priority seen at now is
+// sampled.
+func (s *AdaptiveSampler) computeSampled(priority float32, now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.target == 0 {
+		return false
+	}
+
+	// Start the period now is in, computing the minimum priority from the
+	// traces seen in the period before. Periods without traces in between
+	// reset it.
+	if now.After(s.periodEnd) {
+		elapsed := (now.Sub(s.periodEnd) + s.period - 1) / s.period
+		s.priorityMin = 0
+		if s.numSeen > 0 && elapsed == 1 {
+			s.priorityMin = 1 - float32(s.target)/float32(s.numSeen)
+		}
+		s.numSampled = 0
+		s.numSeen = 0
+		s.periodEnd = s.periodEnd.Add(elapsed * s.period)
+	}
+
+	s.numSeen++
+	if s.numSampled > s.target {
+		// Exponential backoff once the target has been exceeded.
+		threshold := math.Pow(float64(s.target), float64(s.target)/float64(s.numSampled)) - math.Pow(float64(s.target), 0.5)
+		if float64(s.rand.Int63n(int64(s.numSeen))) >= threshold {
+			return false
+		}
+	} else if priority < s.priorityMin {
+		return false
+	}
+	s.numSampled++
+	return true
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAdaptiveSamplerZeroTarget(t *testing.T) {
+	now := time.Now()
+	s := newAdaptiveSampler(0, time.Minute, now)
+	if s.computeSampled(1, now) {
+		t.Error("sampled with a zero target")
+	}
+}
+
+func TestAdaptiveSamplerFirstPeriod(t *testing.T) {
+	now := time.Now()
+	s := newAdaptiveSampler(10, time.Minute, now)
+	for i := 0; i < 11; i++ {
+		if !s.computeSampled(0, now) {
+			t.Fatalf("trace %d of the first period not sampled", i)
+		}
+	}
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if s.computeSampled(0, now) {
+			sampled++
+		}
+	}
+	if sampled > 10 {
+		t.Errorf("sampled %d traces after the target with backoff", sampled)
+	}
+}
+
+func TestAdaptiveSamplerPriorityMin(t *testing.T) {
+	now := time.Now()
+	s := newAdaptiveSampler(2, time.Minute, now)
+	for i := 0; i < 4; i++ {
+		s.computeSampled(0, now)
+	}
+
+	// The minimum priority of the next period is 1 - target/seen.
+	now = now.Add(time.Minute + time.Second)
+	if s.computeSampled(0.4, now) {
+		t.Error("trace with priority below the minimum sampled")
+	}
+	if !s.computeSampled(0.6, now) {
+		t.Error("trace with priority above the minimum not sampled")
+	}
+	if s.priorityMin != 0.5 {
+		t.Errorf("priority minimum: got %v, want 0.5", s.priorityMin)
+	}
+
+	// Periods without traces reset the minimum priority.
+	now = now.Add(3 * time.Minute)
+	if !s.computeSampled(0, now) {
+		t.Error("trace after idle periods not sampled")
+	}
+}
+
+func TestAdaptiveSamplerIdle(t *testing.T) {
+	now := time.Now()
+	s := newAdaptiveSampler(2, time.Millisecond, now)
+	for i := 0; i < 4; i++ {
+		s.computeSampled(0, now)
+	}
+
+	// Periods elapsed while idle are skipped at once.
+	for _, test := range []struct {
+		elapsed       time.Duration
+		wantPeriodEnd time.Duration
+	}{
+		{elapsed: 24 * time.Hour, wantPeriodEnd: 24 * time.Hour},
+		{elapsed: 24*time.Hour + 1500*time.Microsecond, wantPeriodEnd: 24*time.Hour + 2*time.Millisecond},
+	} {
+		if !s.computeSampled(0, now.Add(test.elapsed)) {
+			t.Errorf("%v: trace after idle periods not sampled", test.elapsed)
+		}
+		if got, want := s.periodEnd, now.Add(test.wantPeriodEnd); !got.Equal(want) {
+			t.Errorf("%v: period end: got %v, want %v", test.elapsed, got, want)
+		}
+	}
+}
+
+func TestAdaptiveSamplerParent(t *testing.T) {
+	s := NewAdaptiveSampler(0, time.Minute)
+	traceID := trace.TraceID{1}
+	for _, test := range []struct {
+		name  string
+		flags trace.TraceFlags
+		want  sdktrace.SamplingDecision
+	}{
+		{"sampled parent", trace.FlagsSampled, sdktrace.RecordAndSample},
+		{"unsampled parent", 0, sdktrace.Drop},
+	} {
+		parent := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     trace.SpanID{1},
+			TraceFlags: test.flags,
+		})
+		got := s.ShouldSample(sdktrace.SamplingParameters{
+			ParentContext: trace.ContextWithRemoteSpanContext(context.Background(), parent),
+			TraceID:       traceID,
+		})
+		if got.Decision != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got.Decision, test.want)
+		}
+	}
+}
+
+func TestAdaptiveSamplerPriorityAttribute(t *testing.T) {
+	s := NewAdaptiveSampler(10, time.Minute)
+	got := s.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{1},
+	})
+	if got.Decision != sdktrace.RecordAndSample {
+		t.Fatalf("first root span not sampled: %v", got.Decision)
+	}
+	if len(got.Attributes) != 1 || got.Attributes[0].Key != priorityAttrKey {
+		t.Fatalf("unexpected attributes: %v", got.Attributes)
+	}
+	if p := got.Attributes[0].Value.AsFloat64(); p < 1 || p >= 2 {
+		t.Errorf("sampled priority out of range: %v", p)
+	}
+}
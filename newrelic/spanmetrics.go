@@ -0,0 +1,130 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the metrics derived from spans.
+const (
+	spanCallsMetricName    = "otel.span.calls"
+	spanDurationMetricName = "otel.span.duration"
+)
+
+// overflowSpanName replaces the names of spans once the span name limit of a
+// service has been reached, and the service names once maxSpanMetricsServices
+// has been reached.
+const overflowSpanName = "other"
+
+// maxSpanMetricsServices is the maximum number of distinct services span
+// metrics are tracked for each harvest.
+const maxSpanMetricsServices = 100
+
+// spanMetrics derives request rate, error rate, and duration metrics from
+// server and consumer spans.
+//
+// The metrics are aggregated with the harvester of each span so they are
+// sent, and reset, every harvest. The tracked services and span names are
+// reset with them so the limits apply to each harvest.
+type spanMetrics struct {
+	// maxNames is the maximum number of distinct span names tracked for
+	// each service.
+	maxNames int
+	// maxServices is the maximum number of distinct services tracked.
+	maxServices int
+
+	lock sync.Mutex
+	// names are the span names tracked for each service.
+	names map[string]map[string]struct{}
+}
+
+func newSpanMetrics(maxNames int) *spanMetrics {
+	return &spanMetrics{
+		maxNames:    maxNames,
+		maxServices: maxSpanMetricsServices,
+		names:       make(map[string]map[string]struct{}),
+	}
+}
+
+// reset forgets the tracked services and span names. It is called every
+// harvest.
+func (m *spanMetrics) reset() {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	m.names = make(map[string]map[string]struct{})
+	m.lock.Unlock()
+}
+
+// spanName returns the service and span name span metrics are recorded with.
+// New services and names are replaced with overflowSpanName once their limit
+// has been reached.
+func (m *spanMetrics) spanName(service, name string) (string, string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	names, ok := m.names[service]
+	if !ok {
+		if len(m.names) >= m.maxServices {
+			service = overflowSpanName
+			names = m.names[service]
+		}
+		if names == nil {
+			names = make(map[string]struct{})
+			m.names[service] = names
+		}
+	}
+	if _, ok := names[name]; ok {
+		return service, name
+	}
+	if len(names) >= m.maxNames {
+		return service, overflowSpanName
+	}
+	names[name] = struct{}{}
+	return service, name
+}
+
+// record aggregates the New Relic span, exported for snap, into the call
+// count and duration metrics of hs. Only server and consumer spans are
+// recorded.
+func (m *spanMetrics) record(hs harvesters, span telemetry.Span, snap *sdktrace.SpanSnapshot) {
+	if m == nil {
+		return
+	}
+	if snap.SpanKind != trace.SpanKindServer && snap.SpanKind != trace.SpanKindConsumer {
+		return
+	}
+
+	service, name := m.spanName(span.ServiceName, snap.Name)
+	attrs := map[string]interface{}{
+		"service.name":     service,
+		"span.name":        name,
+		"span.kind":        strings.ToLower(snap.SpanKind.String()),
+		"otel.status_code": statusCodeName(snap.StatusCode),
+	}
+	hs.aggregate(func(agg *telemetry.MetricAggregator) {
+		agg.Count(spanCallsMetricName, attrs).Increment()
+		agg.Summary(spanDurationMetricName, attrs).RecordDuration(span.Duration)
+	})
+}
+
+// statusCodeName returns the OpenTelemetry name of a span status code.
+func statusCodeName(c codes.Code) string {
+	switch c {
+	case codes.Ok:
+		return "OK"
+	case codes.Error:
+		return "ERROR"
+	default:
+		return "UNSET"
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanMetrics(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithSpanMetrics(2))
+
+	now := time.Now()
+	newSpan := func(name string, kind apitrace.SpanKind, code codes.Code, d time.Duration) *trace.SpanSnapshot {
+		return &trace.SpanSnapshot{
+			SpanContext: testSpanContext(),
+			Name:        name,
+			SpanKind:    kind,
+			StatusCode:  code,
+			StartTime:   now,
+			EndTime:     now.Add(d),
+		}
+	}
+	spans := []*trace.SpanSnapshot{
+		newSpan("a", apitrace.SpanKindServer, codes.Unset, time.Second),
+		newSpan("a", apitrace.SpanKindServer, codes.Unset, 3*time.Second),
+		newSpan("a", apitrace.SpanKindServer, codes.Error, time.Second),
+		newSpan("b", apitrace.SpanKindConsumer, codes.Ok, time.Second),
+		// Over the span name limit.
+		newSpan("c", apitrace.SpanKindServer, codes.Unset, time.Second),
+		// Not an entry span.
+		newSpan("d", apitrace.SpanKindClient, codes.Unset, time.Second),
+	}
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	type key struct{ name, spanName, kind, status string }
+	got := make(map[key]interface{})
+	for _, m := range mockt.Metrics() {
+		k := key{
+			name:     m.Name,
+			spanName: m.Attributes["span.name"].(string),
+			kind:     m.Attributes["span.kind"].(string),
+			status:   m.Attributes["otel.status_code"].(string),
+		}
+		if m.Name == spanDurationMetricName {
+			got[k] = m.Value.(map[string]interface{})["sum"]
+			continue
+		}
+		got[k] = m.Value
+	}
+	want := map[key]interface{}{
+		{spanCallsMetricName, "a", "server", "UNSET"}:        float64(2),
+		{spanDurationMetricName, "a", "server", "UNSET"}:     float64(4000),
+		{spanCallsMetricName, "a", "server", "ERROR"}:        float64(1),
+		{spanDurationMetricName, "a", "server", "ERROR"}:     float64(1000),
+		{spanCallsMetricName, "b", "consumer", "OK"}:         float64(1),
+		{spanDurationMetricName, "b", "consumer", "OK"}:      float64(1000),
+		{spanCallsMetricName, "other", "server", "UNSET"}:    float64(1),
+		{spanDurationMetricName, "other", "server", "UNSET"}: float64(1000),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("span metrics: got %v, want %v", got, want)
+	}
+}
+
+func TestSpanMetricsLimits(t *testing.T) {
+	m := newSpanMetrics(1)
+	m.maxServices = 2
+
+	for _, test := range []struct {
+		service, name         string
+		wantService, wantName string
+	}{
+		{"a", "x", "a", "x"},
+		{"a", "y", "a", overflowSpanName},
+		{"b", "x", "b", "x"},
+		// Over the service limit.
+		{"c", "x", overflowSpanName, "x"},
+		{"d", "y", overflowSpanName, overflowSpanName},
+		{"a", "x", "a", "x"},
+	} {
+		service, name := m.spanName(test.service, test.name)
+		if service != test.wantService || name != test.wantName {
+			t.Errorf("%s %s: got %s %s, want %s %s", test.service, test.name, service, name, test.wantService, test.wantName)
+		}
+	}
+
+	// The limits apply to each harvest.
+	m.reset()
+	if service, name := m.spanName("c", "y"); service != "c" || name != "y" {
+		t.Errorf("after reset: got %s %s, want c y", service, name)
+	}
+}
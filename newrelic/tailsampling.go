@@ -0,0 +1,508 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/transform"
+)
+
+// Default limits of a TailSamplingProcessor.
+const (
+	defaultDecisionWait     = 10 * time.Second
+	defaultMaxTraces        = 10000
+	defaultMaxSpansPerTrace = 1000
+)
+
+// maxRateLimitedServices is the maximum number of services a
+// TailSamplingProcessor keeps a rate limiter for.
+const maxRateLimitedServices = 1000
+
+// tailSamplingQueueSize is the number of span batches waiting to be exported
+// by a TailSamplingProcessor.
+const tailSamplingQueueSize = 1000
+
+// errTailSamplingQueueFull is reported when spans are dropped because the
+// queue of spans waiting to be exported is full.
+var errTailSamplingQueueFull = errors.New("tail sampling: queue full, spans dropped")
+
+// TailSamplingPolicy decides if a trace is kept by a TailSamplingProcessor
+// once all of its spans have been buffered.
+type TailSamplingPolicy interface {
+	// ShouldSample returns true if the trace with spans is kept.
+	ShouldSample(spans []*sdktrace.SpanSnapshot) bool
+}
+
+// TailSamplingPolicyFunc is a function that implements TailSamplingPolicy.
+type TailSamplingPolicyFunc func(spans []*sdktrace.SpanSnapshot) bool
+
+// ShouldSample calls f(spans).
+func (f TailSamplingPolicyFunc) ShouldSample(spans []*sdktrace.SpanSnapshot) bool {
+	return f(spans)
+}
+
+// ErrorPolicy keeps traces with any span that has an Error status.
+func ErrorPolicy() TailSamplingPolicy {
+	return TailSamplingPolicyFunc(func(spans []*sdktrace.SpanSnapshot) bool {
+		for _, s := range spans {
+			if s.StatusCode == codes.Error {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// LatencyPolicy keeps traces that last longer than threshold, from the start
+// of their first span to the end of their last.
+func LatencyPolicy(threshold time.Duration) TailSamplingPolicy {
+	return TailSamplingPolicyFunc(func(spans []*sdktrace.SpanSnapshot) bool {
+		var start, end time.Time
+		for _, s := range spans {
+			if start.IsZero() || s.StartTime.Before(start) {
+				start = s.StartTime
+			}
+			if s.EndTime.After(end) {
+				end = s.EndTime
+			}
+		}
+		return end.Sub(start) > threshold
+	})
+}
+
+// AttributePolicy keeps traces with any span that has one of the attributes
+// in kvs.
+func AttributePolicy(kvs ...attribute.KeyValue) TailSamplingPolicy {
+	return TailSamplingPolicyFunc(func(spans []*sdktrace.SpanSnapshot) bool {
+		for _, s := range spans {
+			for _, attr := range s.Attributes {
+				for _, kv := range kvs {
+					if attr == kv {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	})
+}
+
+// ProbabilisticPolicy keeps the given fraction of traces. The decision is
+// derived from the trace ID, so the same traces are kept by every service.
+func ProbabilisticPolicy(fraction float64) TailSamplingPolicy {
+	if fraction >= 1 {
+		return TailSamplingPolicyFunc(func([]*sdktrace.SpanSnapshot) bool { return true })
+	}
+	if fraction < 0 {
+		fraction = 0
+	}
+	upperBound := uint64(fraction * (1 << 63))
+	return TailSamplingPolicyFunc(func(spans []*sdktrace.SpanSnapshot) bool {
+		if len(spans) == 0 {
+			return false
+		}
+		traceID := spans[0].SpanContext.TraceID()
+		return binary.BigEndian.Uint64(traceID[0:8])>>1 < upperBound
+	})
+}
+
+// TailSamplingOption configures a TailSamplingProcessor.
+type TailSamplingOption func(*tailSamplingConfig)
+
+type tailSamplingConfig struct {
+	policies         []TailSamplingPolicy
+	decisionWait     time.Duration
+	maxTraces        int
+	maxSpansPerTrace int
+	// rateLimit is the number of traces kept per second for each service.
+	// Kept traces are not limited if zero.
+	rateLimit float64
+}
+
+// WithTailSamplingPolicies sets the policies that decide which traces are
+// kept. A trace is kept if any policy samples it. Every trace is kept if no
+// policies are set.
+func WithTailSamplingPolicies(policies ...TailSamplingPolicy) TailSamplingOption {
+	return func(cfg *tailSamplingConfig) {
+		cfg.policies = append(cfg.policies, policies...)
+	}
+}
+
+// WithDecisionWait sets how long spans are buffered after the first span of
+// a trace ends before the trace is sampled. The default is 10 seconds.
+func WithDecisionWait(d time.Duration) TailSamplingOption {
+	return func(cfg *tailSamplingConfig) {
+		cfg.decisionWait = d
+	}
+}
+
+// WithMaxTraces sets the maximum number of traces buffered. When it is
+// reached the oldest trace is sampled early. The default is 10000.
+func WithMaxTraces(n int) TailSamplingOption {
+	return func(cfg *tailSamplingConfig) {
+		cfg.maxTraces = n
+	}
+}
+
+// WithMaxSpansPerTrace sets the maximum number of spans buffered for a trace.
+// Additional spans of the trace are dropped, and their number sent to the
+// OpenTelemetry error handler when the trace is sampled. The default is 1000.
+func WithMaxSpansPerTrace(n int) TailSamplingOption {
+	return func(cfg *tailSamplingConfig) {
+		cfg.maxSpansPerTrace = n
+	}
+}
+
+// WithServiceRateLimit limits the traces kept for each service, identified
+// by the Resource `service.name` of the trace's first span, to
+// tracesPerSecond. The rate limits of at most 1000 services are tracked, the
+// least recently used service is reset to make room for a new one.
+func WithServiceRateLimit(tracesPerSecond float64) TailSamplingOption {
+	return func(cfg *tailSamplingConfig) {
+		cfg.rateLimit = tracesPerSecond
+	}
+}
+
+// pendingTrace are the buffered spans of a trace awaiting a decision.
+type pendingTrace struct {
+	spans []*sdktrace.SpanSnapshot
+	// dropped is the number of spans dropped over maxSpansPerTrace.
+	dropped int
+	arrived time.Time
+	elem    *list.Element
+}
+
+// TailSamplingProcessor is a span processor that buffers the spans of each
+// trace for a decision window and exports whole traces that match its
+// policies. Use it in place of a batch span processor with an
+// AlwaysSample sampler so every span reaches it:
+//
+//	tp := sdktrace.NewTracerProvider(
+//		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+//		sdktrace.WithSpanProcessor(newrelic.NewTailSamplingProcessor(exporter,
+//			newrelic.WithTailSamplingPolicies(
+//				newrelic.ErrorPolicy(),
+//				newrelic.LatencyPolicy(time.Second),
+//				newrelic.ProbabilisticPolicy(0.01),
+//			),
+//		)),
+//	)
+//
+// Spans that end after the decision for their trace follow that decision.
+// Sampled spans are exported by a background goroutine, so a slow exporter
+// only fills a bounded queue instead of blocking the application.
+type TailSamplingProcessor struct {
+	exporter sdktrace.SpanExporter
+	cfg      tailSamplingConfig
+
+	lock sync.Mutex
+	// traces are the buffered traces by ID.
+	traces map[trace.TraceID]*pendingTrace
+	// order holds the buffered trace IDs, oldest first.
+	order *list.List
+	// decisions are the recent decisions by trace ID, bounded by
+	// maxTraces. decisionOrder holds the IDs oldest first.
+	decisions     map[trace.TraceID]bool
+	decisionOrder []trace.TraceID
+	// limiters are the rate limiters of each service, at most maxLimiters.
+	limiters    map[string]*rateLimiter
+	maxLimiters int
+
+	// exports are the sampled spans waiting to be exported by run.
+	exports chan []*sdktrace.SpanSnapshot
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ sdktrace.SpanProcessor = (*TailSamplingProcessor)(nil)
+
+// NewTailSamplingProcessor returns a TailSamplingProcessor that exports the
+// sampled traces with exporter, usually an *Exporter.
+func NewTailSamplingProcessor(exporter sdktrace.SpanExporter, options ...TailSamplingOption) *TailSamplingProcessor {
+	cfg := tailSamplingConfig{
+		decisionWait:     defaultDecisionWait,
+		maxTraces:        defaultMaxTraces,
+		maxSpansPerTrace: defaultMaxSpansPerTrace,
+	}
+	for _, o := range options {
+		o(&cfg)
+	}
+	p := &TailSamplingProcessor{
+		exporter:    exporter,
+		cfg:         cfg,
+		traces:      make(map[trace.TraceID]*pendingTrace),
+		order:       list.New(),
+		decisions:   make(map[trace.TraceID]bool),
+		limiters:    make(map[string]*rateLimiter),
+		maxLimiters: maxRateLimitedServices,
+		exports:     make(chan []*sdktrace.SpanSnapshot, tailSamplingQueueSize),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// run exports the queued spans and samples the traces whose decision window
+// has passed until the processor is shut down.
+func (p *TailSamplingProcessor) run() {
+	defer close(p.done)
+	tick := p.cfg.decisionWait / 10
+	if tick < time.Millisecond {
+		tick = time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case spans := <-p.exports:
+			p.export(spans)
+		case now := <-ticker.C:
+			p.export(p.decideExpired(now))
+		}
+	}
+}
+
+// OnStart does nothing.
+func (p *TailSamplingProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd buffers the span until its trace is sampled.
+func (p *TailSamplingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.enqueue(p.add(s.Snapshot(), time.Now()))
+}
+
+// enqueue queues spans to be exported by run. The spans are dropped if the
+// queue is full.
+func (p *TailSamplingProcessor) enqueue(spans []*sdktrace.SpanSnapshot) {
+	if len(spans) == 0 {
+		return
+	}
+	select {
+	case p.exports <- spans:
+	default:
+		otel.Handle(fmt.Errorf("%w: %d spans", errTailSamplingQueueFull, len(spans)))
+	}
+}
+
+// dequeue returns the queued spans without waiting.
+func (p *TailSamplingProcessor) dequeue() []*sdktrace.SpanSnapshot {
+	var spans []*sdktrace.SpanSnapshot
+	for {
+		select {
+		case s := <-p.exports:
+			spans = append(spans, s...)
+		default:
+			return spans
+		}
+	}
+}
+
+// add buffers span and returns any spans that are ready to be exported.
+func (p *TailSamplingProcessor) add(span *sdktrace.SpanSnapshot, now time.Time) []*sdktrace.SpanSnapshot {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	id := span.SpanContext.TraceID()
+	if keep, ok := p.decisions[id]; ok {
+		if keep {
+			return []*sdktrace.SpanSnapshot{span}
+		}
+		return nil
+	}
+
+	t, ok := p.traces[id]
+	if !ok {
+		t = &pendingTrace{arrived: now}
+		t.elem = p.order.PushBack(id)
+		p.traces[id] = t
+	}
+	if len(t.spans) < p.cfg.maxSpansPerTrace {
+		t.spans = append(t.spans, span)
+	} else {
+		t.dropped++
+	}
+
+	if len(p.traces) > p.cfg.maxTraces {
+		return p.decide(p.order.Front().Value.(trace.TraceID), now)
+	}
+	return nil
+}
+
+// decideExpired samples the traces buffered for the decision wait and
+// returns the spans of those kept.
+func (p *TailSamplingProcessor) decideExpired(now time.Time) []*sdktrace.SpanSnapshot {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var spans []*sdktrace.SpanSnapshot
+	for e := p.order.Front(); e != nil; e = p.order.Front() {
+		id := e.Value.(trace.TraceID)
+		if now.Sub(p.traces[id].arrived) < p.cfg.decisionWait {
+			break
+		}
+		spans = append(spans, p.decide(id, now)...)
+	}
+	return spans
+}
+
+// decideAll samples every buffered trace and returns the spans of those
+// kept.
+func (p *TailSamplingProcessor) decideAll(now time.Time) []*sdktrace.SpanSnapshot {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var spans []*sdktrace.SpanSnapshot
+	for e := p.order.Front(); e != nil; e = p.order.Front() {
+		spans = append(spans, p.decide(e.Value.(trace.TraceID), now)...)
+	}
+	return spans
+}
+
+// decide samples the buffered trace id, removes it from the buffer, and
+// returns its spans if it is kept. The lock must be held.
+func (p *TailSamplingProcessor) decide(id trace.TraceID, now time.Time) []*sdktrace.SpanSnapshot {
+	t := p.traces[id]
+	delete(p.traces, id)
+	p.order.Remove(t.elem)
+	if t.dropped > 0 {
+		otel.Handle(fmt.Errorf("tail sampling: trace %s: %d spans over the limit of %d dropped", id, t.dropped, p.cfg.maxSpansPerTrace))
+	}
+
+	keep := len(p.cfg.policies) == 0
+	for _, policy := range p.cfg.policies {
+		if policy.ShouldSample(t.spans) {
+			keep = true
+			break
+		}
+	}
+	if keep && p.cfg.rateLimit > 0 {
+		service := transform.ResolveService("", t.spans[0].Resource).Name
+		keep = p.limiter(service, now).allow(now)
+	}
+
+	p.decisions[id] = keep
+	p.decisionOrder = append(p.decisionOrder, id)
+	if len(p.decisionOrder) > p.cfg.maxTraces {
+		delete(p.decisions, p.decisionOrder[0])
+		p.decisionOrder = p.decisionOrder[1:]
+	}
+
+	if keep {
+		return t.spans
+	}
+	return nil
+}
+
+// limiter returns the rate limiter of service at now. The lock must be held.
+//
+// At most maxLimiters rate limiters are kept. To make room for a new one,
+// those refilled since they were last used are evicted, as a new rate
+// limiter behaves the same, and then the least recently used.
+func (p *TailSamplingProcessor) limiter(service string, now time.Time) *rateLimiter {
+	if l, ok := p.limiters[service]; ok {
+		return l
+	}
+	if len(p.limiters) >= p.maxLimiters {
+		for k, l := range p.limiters {
+			if l.full(now) {
+				delete(p.limiters, k)
+			}
+		}
+	}
+	if len(p.limiters) >= p.maxLimiters {
+		var lruKey string
+		var lru *rateLimiter
+		for k, l := range p.limiters {
+			if lru == nil || l.last.Before(lru.last) {
+				lruKey, lru = k, l
+			}
+		}
+		delete(p.limiters, lruKey)
+	}
+	l := newRateLimiter(p.cfg.rateLimit, now)
+	p.limiters[service] = l
+	return l
+}
+
+// export sends spans to the exporter.
+func (p *TailSamplingProcessor) export(spans []*sdktrace.SpanSnapshot) {
+	if len(spans) == 0 {
+		return
+	}
+	if err := p.exporter.ExportSpans(context.Background(), spans); err != nil {
+		otel.Handle(err)
+	}
+}
+
+// ForceFlush exports the queued spans and samples and exports every buffered
+// trace without waiting for its decision window.
+func (p *TailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	spans := append(p.dequeue(), p.decideAll(time.Now())...)
+	if len(spans) == 0 {
+		return nil
+	}
+	return p.exporter.ExportSpans(ctx, spans)
+}
+
+// Shutdown samples and exports every buffered trace and shuts down the
+// exporter.
+func (p *TailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+	if err := p.ForceFlush(ctx); err != nil {
+		return err
+	}
+	return p.exporter.Shutdown(ctx)
+}
+
+// rateLimiter is a token bucket allowing rate events per second with a burst
+// of one second, or one event if rate is less than one.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, now time.Time) *rateLimiter {
+	burst := math.Max(rate, 1)
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// full returns true if the bucket is full at now.
+func (l *rateLimiter) full(now time.Time) bool {
+	return l.tokens+now.Sub(l.last).Seconds()*l.rate >= l.burst
+}
+
+// allow returns true if an event is allowed at now.
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
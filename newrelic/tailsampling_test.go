@@ -0,0 +1,319 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+// recordingExporter is a SpanExporter that records the exported spans.
+type recordingExporter struct {
+	lock     sync.Mutex
+	spans    []*sdktrace.SpanSnapshot
+	shutdown bool
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []*sdktrace.SpanSnapshot) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+// names returns the names of the exported spans.
+func (e *recordingExporter) names() []string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	var names []string
+	for _, s := range e.spans {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func newTestSnapshot(traceID byte, name string, start time.Time, d time.Duration) *sdktrace.SpanSnapshot {
+	return &sdktrace.SpanSnapshot{
+		SpanContext: apitrace.NewSpanContext(apitrace.SpanContextConfig{
+			TraceID: apitrace.TraceID{traceID},
+			SpanID:  apitrace.SpanID{1},
+		}),
+		Name:      name,
+		StartTime: start,
+		EndTime:   start.Add(d),
+	}
+}
+
+func TestTailSamplingPolicies(t *testing.T) {
+	now := time.Now()
+	ok := newTestSnapshot(1, "ok", now, time.Millisecond)
+	failed := newTestSnapshot(1, "failed", now, time.Millisecond)
+	failed.StatusCode = codes.Error
+	late := newTestSnapshot(1, "late", now.Add(2*time.Second), time.Millisecond)
+	tagged := newTestSnapshot(1, "tagged", now, time.Millisecond)
+	tagged.Attributes = []attribute.KeyValue{attribute.String("customer.tier", "gold")}
+	low := newTestSnapshot(0x00, "low", now, time.Millisecond)
+	high := newTestSnapshot(0xff, "high", now, time.Millisecond)
+
+	for _, test := range []struct {
+		name   string
+		policy TailSamplingPolicy
+		spans  []*sdktrace.SpanSnapshot
+		want   bool
+	}{
+		{"error without error", ErrorPolicy(), []*sdktrace.SpanSnapshot{ok}, false},
+		{"error with error", ErrorPolicy(), []*sdktrace.SpanSnapshot{ok, failed}, true},
+		{"latency under threshold", LatencyPolicy(time.Second), []*sdktrace.SpanSnapshot{ok, failed}, false},
+		{"latency over threshold", LatencyPolicy(time.Second), []*sdktrace.SpanSnapshot{ok, late}, true},
+		{"attribute not matched", AttributePolicy(attribute.String("customer.tier", "silver")), []*sdktrace.SpanSnapshot{ok, tagged}, false},
+		{"attribute matched", AttributePolicy(attribute.String("customer.tier", "gold")), []*sdktrace.SpanSnapshot{ok, tagged}, true},
+		{"probabilistic low trace ID", ProbabilisticPolicy(0.5), []*sdktrace.SpanSnapshot{low}, true},
+		{"probabilistic high trace ID", ProbabilisticPolicy(0.5), []*sdktrace.SpanSnapshot{high}, false},
+		{"probabilistic none", ProbabilisticPolicy(0), []*sdktrace.SpanSnapshot{low}, false},
+		{"probabilistic all", ProbabilisticPolicy(1), []*sdktrace.SpanSnapshot{high}, true},
+	} {
+		if got := test.policy.ShouldSample(test.spans); got != test.want {
+			t.Errorf("%s: got %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestTailSamplingProcessor(t *testing.T) {
+	exp := &recordingExporter{}
+	p := NewTailSamplingProcessor(exp,
+		WithDecisionWait(time.Hour),
+		WithTailSamplingPolicies(ErrorPolicy()),
+	)
+	defer p.Shutdown(context.Background())
+
+	now := time.Now()
+	failed := newTestSnapshot(1, "failed", now, time.Millisecond)
+	failed.StatusCode = codes.Error
+	p.export(p.add(newTestSnapshot(1, "kept", now, time.Millisecond), now))
+	p.export(p.add(failed, now))
+	p.export(p.add(newTestSnapshot(2, "dropped", now, time.Millisecond), now))
+	p.export(p.add(newTestSnapshot(3, "pending", now, time.Millisecond), now.Add(time.Minute)))
+
+	if got := exp.names(); len(got) != 0 {
+		t.Fatalf("spans exported before decision wait: %v", got)
+	}
+
+	p.export(p.decideExpired(now.Add(time.Hour)))
+	if got, want := exp.names(), []string{"kept", "failed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exported spans: got %v, want %v", got, want)
+	}
+
+	// Late spans follow the decision of their trace.
+	p.export(p.add(newTestSnapshot(1, "late kept", now, time.Millisecond), now))
+	p.export(p.add(newTestSnapshot(2, "late dropped", now, time.Millisecond), now))
+	if got, want := exp.names(), []string{"kept", "failed", "late kept"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exported late spans: got %v, want %v", got, want)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !exp.shutdown {
+		t.Error("exporter not shut down")
+	}
+}
+
+func TestTailSamplingProcessorLimits(t *testing.T) {
+	exp := &recordingExporter{}
+	p := NewTailSamplingProcessor(exp,
+		WithDecisionWait(time.Hour),
+		WithMaxTraces(2),
+		WithMaxSpansPerTrace(2),
+	)
+	defer p.Shutdown(context.Background())
+
+	now := time.Now()
+	for _, s := range []*sdktrace.SpanSnapshot{
+		newTestSnapshot(1, "a1", now, 0),
+		newTestSnapshot(1, "a2", now, 0),
+		// Over the span limit of the trace.
+		newTestSnapshot(1, "a3", now, 0),
+		newTestSnapshot(2, "b1", now, 0),
+		// Over the trace limit, the oldest trace is decided early.
+		newTestSnapshot(3, "c1", now, 0),
+	} {
+		p.export(p.add(s, now))
+	}
+	if got, want := exp.names(), []string{"a1", "a2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exported spans: got %v, want %v", got, want)
+	}
+
+	if err := p.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := exp.names(), []string{"a1", "a2", "b1", "c1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("flushed spans: got %v, want %v", got, want)
+	}
+}
+
+func TestTailSamplingProcessorRateLimit(t *testing.T) {
+	exp := &recordingExporter{}
+	p := NewTailSamplingProcessor(exp,
+		WithDecisionWait(time.Hour),
+		WithServiceRateLimit(1),
+	)
+	defer p.Shutdown(context.Background())
+
+	now := time.Now()
+	newSpan := func(traceID byte, service string) *sdktrace.SpanSnapshot {
+		s := newTestSnapshot(traceID, service, now, 0)
+		s.Resource = resource.NewWithAttributes(semconv.ServiceNameKey.String(service))
+		return s
+	}
+	for _, s := range []*sdktrace.SpanSnapshot{
+		newSpan(1, "a"),
+		newSpan(2, "a"),
+		newSpan(3, "b"),
+	} {
+		p.add(s, now)
+	}
+	p.export(p.decideExpired(now.Add(time.Hour)))
+	if got, want := exp.names(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exported spans: got %v, want %v", got, want)
+	}
+}
+
+func TestTailSamplingProcessorRateLimiters(t *testing.T) {
+	p := NewTailSamplingProcessor(&recordingExporter{}, WithServiceRateLimit(1))
+	defer p.Shutdown(context.Background())
+	p.maxLimiters = 2
+
+	now := time.Now()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for i, service := range []string{"a", "b", "c"} {
+		at := now.Add(time.Duration(i) * time.Millisecond)
+		p.limiter(service, at).allow(at)
+	}
+	// The least recently used rate limiter is evicted.
+	if _, ok := p.limiters["a"]; ok || len(p.limiters) != 2 {
+		t.Errorf("rate limiters: got %v, want b and c", p.limiters)
+	}
+	// Refilled rate limiters are evicted first.
+	p.limiter("d", now.Add(time.Minute))
+	if _, ok := p.limiters["d"]; !ok || len(p.limiters) != 1 {
+		t.Errorf("rate limiters: got %v, want d", p.limiters)
+	}
+}
+
+func TestTailSamplingProcessorEndToEnd(t *testing.T) {
+	exp := &recordingExporter{}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSpanProcessor(NewTailSamplingProcessor(exp,
+			WithDecisionWait(10*time.Millisecond),
+			WithTailSamplingPolicies(ErrorPolicy()),
+		)),
+	)
+	tracer := tp.Tracer("test-tracer")
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.SetStatus(codes.Error, "failed")
+	child.End()
+	root.End()
+	_, other := tracer.Start(context.Background(), "other")
+	other.End()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(exp.names()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := exp.names(), []string{"child", "root"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exported spans: got %v, want %v", got, want)
+	}
+}
+
+// blockingExporter is a SpanExporter that blocks exports until released.
+type blockingExporter struct {
+	recordingExporter
+	release chan struct{}
+}
+
+func (e *blockingExporter) ExportSpans(ctx context.Context, spans []*sdktrace.SpanSnapshot) error {
+	<-e.release
+	return e.recordingExporter.ExportSpans(ctx, spans)
+}
+
+func TestTailSamplingProcessorOnEndDoesNotExport(t *testing.T) {
+	exp := &blockingExporter{release: make(chan struct{})}
+	p := NewTailSamplingProcessor(exp,
+		WithDecisionWait(time.Hour),
+		WithMaxTraces(1),
+	)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSpanProcessor(p),
+	)
+	tracer := tp.Tracer("test-tracer")
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		// The second trace is over the trace limit, so the first trace
+		// is decided early and its spans are exported.
+		for _, name := range []string{"a", "b"} {
+			_, span := tracer.Start(context.Background(), name)
+			span.End()
+		}
+	}()
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ending spans blocked on the exporter")
+	}
+
+	close(exp.release)
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := exp.names(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exported spans: got %v, want %v", got, want)
+	}
+}
+
+func TestTailSamplingProcessorSharedExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e := newTestExporter(t, "service", &MockTransport{}, WithDebugSink(filepath.Join(dir, "payloads.json")))
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(e),
+		sdktrace.WithSpanProcessor(NewTailSamplingProcessor(e)),
+	)
+	// The Exporter is shut down by both span processors.
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Errorf("shutting down: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"time"
+
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/transform"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Names of the metrics derived from transactions.
+const (
+	apdexMetricName               = "apm.service.apdex"
+	transactionDurationMetricName = "apm.service.transaction.duration"
+)
+
+// defaultApdexThreshold is the default Apdex threshold of New Relic agents.
+const defaultApdexThreshold = 500 * time.Millisecond
+
+// transactions maps entry spans to New Relic APM transactions.
+type transactions struct {
+	// apdexT is the Apdex threshold of web transactions.
+	apdexT time.Duration
+}
+
+// record marks span as the entry span of a transaction if snap is the entry
+// span of a service, and records the Transaction event and metrics of the
+// transaction with hs. The timing of the transaction is that of span, as
+// repaired by validation. The common attributes are copied into the event.
+func (t *transactions) record(hs harvesters, span *telemetry.Span, snap *sdktrace.SpanSnapshot, common map[string]interface{}) error {
+	if t == nil {
+		return nil
+	}
+	txn, ok := transform.EntryTransaction(snap)
+	if !ok {
+		return nil
+	}
+	txn.Mark(span)
+
+	d := span.Duration
+	zone := transform.ApdexZone(d, t.apdexT, snap.StatusCode == codes.Error)
+	attrs := map[string]interface{}{
+		"service.name":    span.ServiceName,
+		"transactionName": txn.Name,
+		"transactionType": txn.Type,
+	}
+	var apdexAttrs map[string]interface{}
+	if txn.Type == transform.TransactionTypeWeb {
+		apdexAttrs = map[string]interface{}{"apdex.zone": zone}
+		for k, v := range attrs {
+			apdexAttrs[k] = v
+		}
+	}
+	hs.aggregate(func(agg *telemetry.MetricAggregator) {
+		agg.Summary(transactionDurationMetricName, attrs).Record(d.Seconds())
+		if apdexAttrs != nil {
+			agg.Count(apdexMetricName, apdexAttrs).Increment()
+		}
+	})
+
+	return hs.RecordEvent(txn.Event(*span, snap, common, zone))
+}
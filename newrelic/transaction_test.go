@@ -0,0 +1,85 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestTransactions(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithTransactions(0))
+
+	childID, _ := apitrace.SpanIDFromHex("b7ad6b7169203331")
+	root := testSpanContext()
+	now := time.Now()
+	spans := []*trace.SpanSnapshot{
+		{
+			SpanContext: root,
+			Name:        "GET /users",
+			SpanKind:    apitrace.SpanKindServer,
+			StartTime:   now,
+			EndTime:     now.Add(time.Second),
+			Attributes:  []attribute.KeyValue{attribute.String("http.method", "GET")},
+		},
+		{
+			SpanContext: apitrace.NewSpanContext(apitrace.SpanContextConfig{TraceID: testTraceID, SpanID: childID}),
+			Parent:      root,
+			Name:        "query",
+			SpanKind:    apitrace.SpanKindClient,
+			StartTime:   now,
+			EndTime:     now.Add(time.Millisecond),
+		},
+	}
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	for _, s := range mockt.Spans() {
+		_, entry := s.Attributes["nr.entryPoint"]
+		if want := s.ID == testSpanID.String(); entry != want {
+			t.Errorf("span %s: entry point %t, want %t", s.ID, entry, want)
+		}
+	}
+
+	if got := len(mockt.Events); got != 1 {
+		t.Fatalf("expecting 1 transaction event, got %d", got)
+	}
+	event := mockt.Events[0]
+	for k, want := range map[string]interface{}{
+		"eventType":        "Transaction",
+		"name":             "WebTransaction/Go/GET /users",
+		"appName":          "service",
+		"service.name":     "service",
+		"nr.apdexPerfZone": "T",
+		"guid":             testSpanID.String(),
+	} {
+		if got := event[k]; got != want {
+			t.Errorf("transaction event %s: got %v, want %v", k, got, want)
+		}
+	}
+
+	got := make(map[string]interface{})
+	for _, m := range mockt.Metrics() {
+		got[m.Name] = m.Attributes["transactionName"]
+		if m.Name == apdexMetricName {
+			if zone := m.Attributes["apdex.zone"]; zone != "T" {
+				t.Errorf("apdex zone: got %v, want T", zone)
+			}
+		}
+	}
+	for _, name := range []string{apdexMetricName, transactionDurationMetricName} {
+		if got[name] != "WebTransaction/Go/GET /users" {
+			t.Errorf("%s transaction name: got %v", name, got[name])
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"github.com/newrelic/newrelic-telemetry-sdk-go/telemetry"
+	"go.opentelemetry.io/otel"
+
+	"github.com/newrelic/opentelemetry-exporter-go/newrelic/internal/transform"
+)
+
+// ValidationMode is how the Exporter handles spans and metrics that New
+// Relic would not accept.
+type ValidationMode int
+
+const (
+	// ValidationLenient repairs invalid spans and metrics where possible:
+	// negative durations and intervals are set to zero, out of range
+	// timestamps to the current time, non-scalar attribute values are
+	// converted to strings, overlong attribute values and metric names are
+	// truncated, and attributes over the New Relic limits are removed. Data
+	// that cannot be repaired, such as spans with invalid IDs or metrics
+	// with non-finite values, is dropped and reported to the OpenTelemetry
+	// error handler. The repaired spans are also what transactions and span
+	// metrics are derived from.
+	ValidationLenient ValidationMode = iota + 1
+	// ValidationStrict drops invalid spans and metrics and returns an error
+	// for each from the export.
+	ValidationStrict
+)
+
+// Names of the validation self-metrics.
+const (
+	validationRepairsMetricName = "newrelic.exporter.validation.repairs"
+	validationDroppedMetricName = "newrelic.exporter.validation.dropped"
+)
+
+// newValidator returns the Validator of mode, nil if mode is not a
+// ValidationMode.
+func newValidator(mode ValidationMode) *transform.Validator {
+	switch mode {
+	case ValidationLenient:
+		return &transform.Validator{}
+	case ValidationStrict:
+		return &transform.Validator{Strict: true}
+	}
+	return nil
+}
+
+// invalidError returns err, why validation dropped a span or metric, if the
+// validation is strict so it is returned from the export. Lenient validation
+// sends it to the OpenTelemetry error handler instead and returns nil.
+func (e *Exporter) invalidError(err error) error {
+	if e.validator.Strict {
+		return err
+	}
+	otel.Handle(err)
+	return nil
+}
+
+// recordValidation aggregates the repairs made to a span or metric of
+// service, and whether it was dropped, into exporter self-metrics.
+func recordValidation(hs harvesters, service, signal string, v transform.Validation, dropped bool) {
+	if v.Repairs == 0 && !dropped {
+		return
+	}
+	attrs := map[string]interface{}{
+		"service.name": service,
+		"signal":       signal,
+	}
+	hs.aggregate(func(agg *telemetry.MetricAggregator) {
+		if v.Repairs > 0 {
+			agg.Count(validationRepairsMetricName, attrs).Increase(float64(v.Repairs))
+		}
+		if dropped {
+			agg.Count(validationDroppedMetricName, attrs).Increase(1)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/number"
+	exportmetric "go.opentelemetry.io/otel/sdk/export/metric"
+	sumAgg "go.opentelemetry.io/otel/sdk/metric/aggregator/sum"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	apitrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestValidation(t *testing.T) {
+	// Spans too old for New Relic to accept.
+	newSpans := func() []*trace.SpanSnapshot {
+		start := time.Now().Add(-72 * time.Hour)
+		spans := newTestSpans("old", "old")
+		for _, s := range spans {
+			s.StartTime = start
+			s.EndTime = start.Add(time.Second)
+		}
+		return spans
+	}
+
+	for _, test := range []struct {
+		mode    ValidationMode
+		spans   int
+		repairs float64
+	}{
+		{mode: ValidationLenient, spans: 2, repairs: 2},
+		{mode: ValidationStrict, spans: 0},
+	} {
+		mockt := &MockTransport{}
+		e := newTestExporter(t, "service", mockt, WithValidation(test.mode))
+		ctx := context.Background()
+		err := e.ExportSpans(ctx, newSpans())
+		if test.mode == ValidationStrict && err == nil {
+			t.Errorf("mode %d: expected an error for the invalid spans", test.mode)
+		} else if test.mode == ValidationLenient && err != nil {
+			t.Errorf("mode %d: unexpected error: %v", test.mode, err)
+		}
+		if err := e.Shutdown(ctx); err != nil {
+			t.Fatalf("shutting down exporter: %v", err)
+		}
+
+		spans := mockt.Spans()
+		if len(spans) != test.spans {
+			t.Errorf("mode %d: expected %d spans, got %d", test.mode, test.spans, len(spans))
+		}
+		metrics := make(map[string]float64)
+		for _, m := range mockt.Metrics() {
+			metrics[m.Name] += m.Value.(float64)
+		}
+		if got := metrics[validationRepairsMetricName]; got != test.repairs {
+			t.Errorf("mode %d: repairs: got %v, want %v", test.mode, got, test.repairs)
+		}
+		if got, want := metrics[validationDroppedMetricName], float64(2-test.spans); got != want {
+			t.Errorf("mode %d: dropped: got %v, want %v", test.mode, got, want)
+		}
+	}
+}
+
+func TestValidationLenientDerivedData(t *testing.T) {
+	mockt := &MockTransport{}
+	e := newTestExporter(t, "service", mockt, WithValidation(ValidationLenient), WithTransactions(0))
+
+	// A server span too old for New Relic to accept.
+	spans := newTestSpans("GET /users")
+	start := time.Now().Add(-72 * time.Hour)
+	spans[0].SpanKind = apitrace.SpanKindServer
+	spans[0].StartTime = start
+	spans[0].EndTime = start.Add(time.Second)
+	ctx := context.Background()
+	if err := e.ExportSpans(ctx, spans); err != nil {
+		t.Fatalf("exporting spans: %v", err)
+	}
+	e.harvestNow(ctx)
+
+	if got := len(mockt.Events); got != 1 {
+		t.Fatalf("expecting 1 transaction event, got %d", got)
+	}
+	// The event has the repaired timestamp of the span.
+	ts := time.Unix(0, int64(mockt.Events[0]["timestamp"].(float64))*int64(time.Millisecond))
+	if time.Since(ts) > time.Hour {
+		t.Errorf("transaction event timestamp not repaired: %v", ts)
+	}
+}
+
+func TestValidationMetrics(t *testing.T) {
+	for _, test := range []struct {
+		mode    ValidationMode
+		wantErr bool
+	}{
+		{mode: ValidationLenient, wantErr: false},
+		{mode: ValidationStrict, wantErr: true},
+	} {
+		mockt := &MockTransport{}
+		e := newTestExporter(t, "service", mockt, WithValidation(test.mode))
+
+		ctx := context.Background()
+		desc := metric.NewDescriptor("metric", metric.CounterInstrumentKind, number.Float64Kind)
+		agg := sumAgg.New(1)[0]
+		if err := agg.Update(ctx, number.NewFloat64Number(math.Inf(1)), &desc); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		labels := attribute.NewSet()
+		cps := &checkpointSet{records: []exportmetric.Record{
+			exportmetric.NewRecord(&desc, &labels, resource.Empty(), &agg, now, now),
+		}}
+		// The metric cannot be repaired and is dropped in either mode.
+		if err := e.Export(ctx, cps); (err != nil) != test.wantErr {
+			t.Errorf("mode %d: got error %v, want error %t", test.mode, err, test.wantErr)
+		}
+		e.harvestNow(ctx)
+
+		var dropped float64
+		for _, m := range mockt.Metrics() {
+			if m.Name == "metric" {
+				t.Errorf("mode %d: invalid metric sent", test.mode)
+			}
+			if m.Name == validationDroppedMetricName {
+				dropped += m.Value.(float64)
+			}
+		}
+		if dropped != 1 {
+			t.Errorf("mode %d: dropped: got %v, want 1", test.mode, dropped)
+		}
+	}
+}